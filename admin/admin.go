@@ -0,0 +1,198 @@
+package admin
+
+import (
+	"errors"
+	"gitee.com/magicianlyx/GoTask"
+	"gitee.com/magicianlyx/GoTask/pool"
+	jsoniter "github.com/json-iterator/go"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrNotFound         = errors.New("not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// 任务操作
+const (
+	ActionExecute = "execute"
+	ActionCancel  = "cancel"
+	ActionBan     = "ban"
+	ActionUnBan   = "unban"
+)
+
+type Options struct {
+	Pool *pool.GoroutinePool // 在统计接口中展示的线程池 为空时不展示
+	Auth Middleware          // 修改类接口的鉴权中间件 为空时不鉴权 查询类接口需要鉴权时可包装整个Handler
+}
+
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return &Options{}
+	}
+	return &Options{
+		Pool: o.Pool,
+		Auth: o.Auth,
+	}
+}
+
+// 定时任务组件的管理接口 以JSON格式响应
+//
+//	GET  /tasks              任务列表
+//	GET  /tasks/{key}        任务信息
+//	POST /tasks/{key}/execute 主动执行一次任务
+//	POST /tasks/{key}/cancel  取消任务
+//	POST /tasks/{key}/ban     禁止key 同时取消任务
+//	POST /tasks/{key}/unban   解除禁止key
+//	GET  /stats              定时任务组件及线程池状态
+//
+// key需要进行url编码 挂载到非根路径时需配合http.StripPrefix使用
+type Handler struct {
+	tt     *GoTask.TimedTask
+	o      *Options
+	mutate http.Handler // 经过鉴权中间件包装的修改类接口
+}
+
+func NewHandler(tt *GoTask.TimedTask, options *Options) *Handler {
+	h := &Handler{tt: tt, o: options.Clone()}
+	h.mutate = http.HandlerFunc(h.serveAction)
+	if h.o.Auth != nil {
+		h.mutate = h.o.Auth(h.mutate)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	switch {
+	case path == "tasks":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, newTaskViews(h.tt.GetTimedTaskInfo()))
+	case path == "stats":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		h.serveStats(w)
+	case strings.HasPrefix(path, "tasks/"):
+		if r.Method == http.MethodPost {
+			h.mutate.ServeHTTP(w, r)
+			return
+		}
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		key, err := url.PathUnescape(strings.TrimPrefix(path, "tasks/"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.serveTask(w, key)
+	default:
+		writeError(w, http.StatusNotFound, ErrNotFound)
+	}
+}
+
+func (h *Handler) serveTask(w http.ResponseWriter, key string) {
+	ti, ok := h.tt.GetTaskInfo(key)
+	if !ok {
+		writeError(w, http.StatusNotFound, GoTask.ErrTaskIsNotExist)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskView(ti))
+}
+
+func (h *Handler) serveStats(w http.ResponseWriter) {
+	v := &StatsView{Scheduler: newSchedulerView(h.tt)}
+	if h.o.Pool != nil {
+		v.Pool = newPoolView(h.o.Pool)
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// 处理/tasks/{key}/{action}
+func (h *Handler) serveAction(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(strings.Trim(r.URL.EscapedPath(), "/"), "tasks/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		writeError(w, http.StatusNotFound, ErrNotFound)
+		return
+	}
+	key, err := url.PathUnescape(path[:i])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch path[i+1:] {
+	case ActionExecute:
+		if !h.tt.IsExist(key) {
+			writeError(w, http.StatusNotFound, GoTask.ErrTaskIsNotExist)
+			return
+		}
+		h.tt.Execute(key)
+		w.WriteHeader(http.StatusAccepted)
+	case ActionCancel:
+		ti, err := h.tt.CancelSync(key)
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newTaskView(ti))
+	case ActionBan:
+		if _, err := h.tt.BanSync(key); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case ActionUnBan:
+		if err := h.tt.UnBanSync(key); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, ErrNotFound)
+	}
+}
+
+// 错误对应的http状态码
+func statusOf(err error) int {
+	switch err {
+	case GoTask.ErrTaskIsNotExist:
+		return http.StatusNotFound
+	case GoTask.ErrTaskIsBan, GoTask.ErrTaskIsUnBan:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// 请求方法不符时响应405 返回是否允许
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	bs, err := jsoniter.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		bs, _ = jsoniter.Marshal(&ErrorView{Error: err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(bs)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &ErrorView{Error: err.Error()})
+}
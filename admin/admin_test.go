@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"gitee.com/magicianlyx/GoTask"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/task"
+	jsoniter "github.com/json-iterator/go"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestHandler(t *testing.T) (*GoTask.TimedTask, *pool.GoroutinePool, *Handler) {
+	tt := GoTask.NewTimedTask(1)
+	p := pool.NewGoroutinePool(&pool.Options{})
+	for _, key := range []string{"b", "a/1"} {
+		sche, err := task.NewCronSchedule("0 0 1 1 *")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tt.AddSync(key, func() (map[string]interface{}, error) {
+			return nil, nil
+		}, sche); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tt, p, NewHandler(tt, &Options{Pool: p, Auth: BearerToken("secret")})
+}
+
+func do(h http.Handler, method, path, token string, v interface{}) int {
+	r := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if v != nil {
+		jsoniter.Unmarshal(w.Body.Bytes(), v)
+	}
+	return w.Code
+}
+
+func TestHandler_Query(t *testing.T) {
+	tt, p, h := newTestHandler(t)
+	defer tt.Stop()
+	defer p.Stop()
+
+	var list []*TaskView
+	if code := do(h, "GET", "/tasks", "", &list); code != http.StatusOK {
+		t.Fatalf("list: %d", code)
+	}
+	if len(list) != 2 || list[0].Key != "a/1" || list[1].Key != "b" || list[1].Schedule != "0 0 1 1 *" {
+		t.Errorf("unexpected list: %+v", list)
+	}
+
+	var v TaskView
+	if code := do(h, "GET", "/tasks/a%2F1", "", &v); code != http.StatusOK || v.Key != "a/1" || !v.HasNext {
+		t.Errorf("view: %d %+v", code, v)
+	}
+	var e ErrorView
+	if code := do(h, "GET", "/tasks/missing", "", &e); code != http.StatusNotFound || e.Error != GoTask.ErrTaskIsNotExist.Error() {
+		t.Errorf("missing: %d %+v", code, e)
+	}
+	if code := do(h, "DELETE", "/tasks", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("method: %d", code)
+	}
+
+	var stats StatsView
+	if code := do(h, "GET", "/stats", "", &stats); code != http.StatusOK {
+		t.Fatalf("stats: %d", code)
+	}
+	if stats.Scheduler == nil || stats.Scheduler.Tasks != 2 || stats.Pool == nil || len(stats.Pool.WorkByPriority) != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestHandler_Action(t *testing.T) {
+	tt, p, h := newTestHandler(t)
+	defer tt.Stop()
+	defer p.Stop()
+	executed := make(chan string, 1)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		executed <- a.Key
+	})
+
+	if code := do(h, "POST", "/tasks/b/execute", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("execute without token: %d", code)
+	}
+	if code := do(h, "POST", "/tasks/b/execute", "wrong", nil); code != http.StatusUnauthorized {
+		t.Errorf("execute with wrong token: %d", code)
+	}
+	if code := do(h, "POST", "/tasks/b/execute", "secret", nil); code != http.StatusAccepted {
+		t.Errorf("execute: %d", code)
+	}
+	select {
+	case key := <-executed:
+		if key != "b" {
+			t.Errorf("executed %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task not executed")
+	}
+
+	var v TaskView
+	if code := do(h, "POST", "/tasks/a%2F1/cancel", "secret", &v); code != http.StatusOK || v.Key != "a/1" || tt.IsExist("a/1") {
+		t.Errorf("cancel: %d %+v", code, v)
+	}
+	if code := do(h, "POST", "/tasks/a%2F1/cancel", "secret", nil); code != http.StatusNotFound {
+		t.Errorf("cancel missing: %d", code)
+	}
+
+	if code := do(h, "POST", "/tasks/b/ban", "secret", nil); code != http.StatusNoContent || !tt.IsBan("b") || tt.IsExist("b") {
+		t.Errorf("ban: %d", code)
+	}
+	if code := do(h, "POST", "/tasks/b/ban", "secret", nil); code != http.StatusConflict {
+		t.Errorf("ban twice: %d", code)
+	}
+	if code := do(h, "POST", "/tasks/b/unban", "secret", nil); code != http.StatusNoContent || tt.IsBan("b") {
+		t.Errorf("unban: %d", code)
+	}
+	if code := do(h, "POST", "/tasks/b/unban", "secret", nil); code != http.StatusConflict {
+		t.Errorf("unban twice: %d", code)
+	}
+	if code := do(h, "POST", "/tasks/b/unknown", "secret", nil); code != http.StatusNotFound {
+		t.Errorf("unknown action: %d", code)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	for _, token := range []string{"secret", ""} {
+		h := BearerToken(token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		for _, c := range []struct {
+			header string
+			code   int
+		}{
+			{"Bearer secret", http.StatusNoContent},
+			{"Bearer wrong", http.StatusUnauthorized},
+			{"secret", http.StatusUnauthorized},
+			{"Basic secret", http.StatusUnauthorized},
+			{"Bearer ", http.StatusUnauthorized},
+			{"", http.StatusUnauthorized},
+		} {
+			if token == "" {
+				// 未配置token时任何请求都被拒绝
+				c.code = http.StatusUnauthorized
+			}
+			r := httptest.NewRequest("POST", "/", nil)
+			if c.header != "" {
+				r.Header.Set("Authorization", c.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.code {
+				t.Errorf("token %q header %q: got %d, want %d", token, c.header, w.Code, c.code)
+			}
+		}
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := BasicAuth("ops", "pass")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for _, c := range []struct {
+		user, password string
+		code           int
+	}{
+		{"ops", "pass", http.StatusNoContent},
+		{"ops", "wrong", http.StatusUnauthorized},
+		{"other", "pass", http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.SetBasicAuth(c.user, c.password)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s:%s got %d, want %d", c.user, c.password, w.Code, c.code)
+		}
+	}
+}
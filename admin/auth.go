@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// http中间件 用于为修改类接口添加鉴权
+type Middleware func(next http.Handler) http.Handler
+
+// 依次应用多个中间件 第一个中间件最先处理请求
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// 校验Authorization: Bearer <token> 缺少Bearer前缀或token为空时拒绝
+func BearerToken(token string) Middleware {
+	const prefix = "Bearer "
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			got := strings.TrimPrefix(header, prefix)
+			if !strings.HasPrefix(header, prefix) || got == "" || !equal(got, token) {
+				writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// 校验http基本认证
+func BasicAuth(user, password string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, p, ok := r.BasicAuth()
+			// 两者都比较 防止通过耗时判断用户名是否正确
+			userOk, passwordOk := equal(u, user), equal(p, password)
+			if !ok || !userOk || !passwordOk {
+				w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+				writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// 常量时间比较
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
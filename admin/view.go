@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"gitee.com/magicianlyx/GoTask"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/task"
+	"sort"
+	"time"
+)
+
+// 任务执行结果
+type ResultView struct {
+	Result map[string]interface{} `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// 任务信息
+type TaskView struct {
+	Key        string      `json:"key"`
+	Name       string      `json:"name,omitempty"`     // 通过名称添加时的任务方法名称
+	Schedule   string      `json:"schedule,omitempty"` // 调度器的ISchedule.ToString()
+	Priority   string      `json:"priority"`
+	AddTime    time.Time   `json:"addTime"`
+	LastTime   time.Time   `json:"lastTime"`
+	NextTime   time.Time   `json:"nextTime"`
+	Count      int         `json:"count"`
+	Misfired   int         `json:"misfired"`
+	HasNext    bool        `json:"hasNext"`
+	Paused     bool        `json:"paused"`
+	PausedAt   time.Time   `json:"pausedAt,omitempty"`
+	LastResult *ResultView `json:"lastResult,omitempty"`
+}
+
+func newTaskView(ti *task.TaskInfo) *TaskView {
+	v := &TaskView{
+		Key:      ti.Key,
+		Name:     ti.Name,
+		Priority: ti.GetOptions().Priority.ToString(),
+		AddTime:  ti.AddTime,
+		LastTime: ti.LastTime,
+		NextTime: ti.NextTime,
+		Count:    ti.Count,
+		Misfired: ti.Misfired,
+		HasNext:  ti.HasNext,
+		Paused:   ti.Paused,
+		PausedAt: ti.PausedTime,
+	}
+	if ti.Sche != nil {
+		v.Schedule = ti.Sche.ToString()
+	}
+	if ti.LastResult != nil {
+		v.LastResult = &ResultView{Result: ti.LastResult.Result}
+		if ti.LastResult.Err != nil {
+			v.LastResult.Error = ti.LastResult.Err.Error()
+		}
+	}
+	return v
+}
+
+// 按key排序的任务列表
+func newTaskViews(m map[string]*task.TaskInfo) []*TaskView {
+	views := make([]*TaskView, 0, len(m))
+	for _, ti := range m {
+		views = append(views, newTaskView(ti))
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Key < views[j].Key
+	})
+	return views
+}
+
+// 某一优先级排队中的任务数
+type QueueDepthView struct {
+	Priority string `json:"priority"`
+	Count    int    `json:"count"`
+}
+
+// 定时任务组件状态
+type SchedulerView struct {
+	Tasks      int               `json:"tasks"`
+	BannedKeys int               `json:"bannedKeys"`
+	PausedAll  bool              `json:"pausedAll"`
+	PausedAt   time.Time         `json:"pausedAt,omitempty"`
+	QueueDepth []*QueueDepthView `json:"queueDepth"` // 按优先级从高到低排列
+	Counters   *GoTask.Counters  `json:"counters"`
+}
+
+func newSchedulerView(tt *GoTask.TimedTask) *SchedulerView {
+	v := &SchedulerView{
+		Tasks:      len(tt.GetTimedTaskInfo()),
+		BannedKeys: tt.GetBanCount(),
+		QueueDepth: make([]*QueueDepthView, 0),
+		Counters:   tt.GetCounters(),
+	}
+	v.PausedAt, v.PausedAll = tt.IsPausedAll()
+	depth := tt.GetQueueDepth()
+	priorities := make([]task.Priority, 0, len(depth))
+	for p := range depth {
+		priorities = append(priorities, p)
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i] > priorities[j]
+	})
+	for _, p := range priorities {
+		v.QueueDepth = append(v.QueueDepth, &QueueDepthView{Priority: p.ToString(), Count: depth[p]})
+	}
+	return v
+}
+
+// 线程池状态
+type PoolView struct {
+	ActiveCount    int           `json:"activeCount"`    // 活跃线程数
+	GoroutineCount int           `json:"goroutineCount"` // 存活线程数
+	GoroutinePeak  int           `json:"goroutinePeak"`  // 存活线程峰值
+	WorkCount      int           `json:"workCount"`      // 排队中的任务数
+	WorkByPriority []int         `json:"workByPriority"` // 各优先级排队中的任务数 下标为优先级
+	RejectedCount  int64         `json:"rejectedCount"`  // 被拒绝的任务数
+	ActiveDuration time.Duration `json:"activeDuration"` // 线程处于活跃状态的总时长
+	SleepDuration  time.Duration `json:"sleepDuration"`  // 线程处于休眠状态的总时长
+}
+
+func newPoolView(p *pool.GoroutinePool) *PoolView {
+	settle := p.GetStatusSettle()
+	return &PoolView{
+		ActiveCount:    p.GetCurrentActiveCount(),
+		GoroutineCount: p.GetGoroutineCount(),
+		GoroutinePeak:  p.GetGoroutinePeak(),
+		WorkCount:      p.GetWorkCount(),
+		WorkByPriority: p.GetWorkCountByPriority(),
+		RejectedCount:  p.GetRejectedCount(),
+		ActiveDuration: settle[pool.GoroutineStatusActive],
+		SleepDuration:  settle[pool.GoroutineStatusSleep],
+	}
+}
+
+// 组件状态
+type StatsView struct {
+	Scheduler *SchedulerView `json:"scheduler"`
+	Pool      *PoolView      `json:"pool,omitempty"` // 未配置线程池时为空
+}
+
+// 错误响应
+type ErrorView struct {
+	Error string `json:"error"`
+}
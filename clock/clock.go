@@ -0,0 +1,83 @@
+package clock
+
+import "time"
+
+// 时钟接口 用于替换time包中与当前时间相关的方法 便于测试
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// 定时器接口 对应time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// 周期定时器接口 对应time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// 系统时钟
+type RealClock struct{}
+
+func NewRealClock() *RealClock {
+	return &RealClock{}
+}
+
+func (c *RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *RealClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (c *RealClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+func (c *RealClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{time.NewTicker(d)}
+}
+
+func (c *RealClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{time.AfterFunc(d, f)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t *realTicker) Stop() {
+	t.t.Stop()
+}
+
+// 为空时返回系统时钟
+func OrDefault(c Clock) Clock {
+	if c == nil {
+		return NewRealClock()
+	}
+	return c
+}
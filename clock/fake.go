@@ -0,0 +1,166 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 手动时钟（线程安全） 时间只会在调用Advance或Set时前进
+// 前进时按到期时刻顺序触发所有到期的定时器
+type FakeClock struct {
+	l       sync.Mutex
+	c       *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	fc := &FakeClock{now: now}
+	fc.c = sync.NewCond(&fc.l)
+	return fc
+}
+
+// 等待中的定时器
+type fakeWaiter struct {
+	fc     *FakeClock
+	until  time.Time     // 到期时刻
+	period time.Duration // 周期定时器的周期 非周期定时器为0
+	ch     chan time.Time
+	f      func() // AfterFunc的回调方法
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	return fc.now
+}
+
+func (fc *FakeClock) Since(t time.Time) time.Duration {
+	return fc.Now().Sub(t)
+}
+
+func (fc *FakeClock) addWaiter(w *fakeWaiter) {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	if !w.until.After(fc.now) && w.period == 0 {
+		// 已经到期 立即触发
+		w.fire(fc.now)
+		return
+	}
+	fc.waiters = append(fc.waiters, w)
+	fc.c.Broadcast()
+}
+
+func (fc *FakeClock) removeWaiter(w *fakeWaiter) bool {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	for i := range fc.waiters {
+		if fc.waiters[i] == w {
+			fc.waiters = append(fc.waiters[:i], fc.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (fc *FakeClock) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{fc: fc, until: fc.Now().Add(d), ch: make(chan time.Time, 1)}
+	fc.addWaiter(w)
+	return w
+}
+
+func (fc *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{fc: fc, until: fc.Now().Add(d), period: d, ch: make(chan time.Time, 1)}
+	fc.addWaiter(w)
+	return &fakeTicker{w}
+}
+
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	w := &fakeWaiter{fc: fc, until: fc.Now().Add(d), f: f}
+	fc.addWaiter(w)
+	return w
+}
+
+// 时间前进d 按顺序触发期间所有到期的定时器
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.Set(fc.Now().Add(d))
+}
+
+// 设置当前时间 只能前进 按顺序触发期间所有到期的定时器
+func (fc *FakeClock) Set(t time.Time) {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	for {
+		sort.SliceStable(fc.waiters, func(i, j int) bool {
+			return fc.waiters[i].until.Before(fc.waiters[j].until)
+		})
+		if len(fc.waiters) == 0 || fc.waiters[0].until.After(t) {
+			break
+		}
+		w := fc.waiters[0]
+		if w.until.After(fc.now) {
+			fc.now = w.until
+		}
+		if w.period > 0 {
+			w.until = w.until.Add(w.period)
+		} else {
+			fc.waiters = fc.waiters[1:]
+		}
+		w.fire(fc.now)
+	}
+	if t.After(fc.now) {
+		fc.now = t
+	}
+}
+
+// 阻塞直到至少有n个定时器在等待 用于等待被测试的线程进入等待状态
+func (fc *FakeClock) BlockUntil(n int) {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	for len(fc.waiters) < n {
+		fc.c.Wait()
+	}
+}
+
+// 当前等待中的定时器数
+func (fc *FakeClock) WaiterCount() int {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	return len(fc.waiters)
+}
+
+// 触发定时器 与time包一致 通道已满时丢弃本次触发
+func (w *fakeWaiter) fire(now time.Time) {
+	if w.f != nil {
+		go w.f()
+		return
+	}
+	select {
+	case w.ch <- now:
+	default:
+	}
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	return w.fc.removeWaiter(w)
+}
+
+type fakeTicker struct {
+	w *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.w.Stop()
+}
@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock_Advance(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fc := NewFakeClock(start)
+
+	timer := fc.NewTimer(3 * time.Second)
+	ticker := fc.NewTicker(time.Second)
+	called := make(chan time.Time, 1)
+	fc.AfterFunc(2*time.Second, func() {
+		called <- fc.Now()
+	})
+
+	fc.Advance(1500 * time.Millisecond)
+	select {
+	case now := <-ticker.C():
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("ticker fired at %v", now)
+		}
+	default:
+		t.Fatal("ticker not fired")
+	}
+
+	fc.Advance(2 * time.Second)
+	select {
+	case now := <-timer.C():
+		if !now.Equal(start.Add(3 * time.Second)) {
+			t.Errorf("timer fired at %v", now)
+		}
+	default:
+		t.Fatal("timer not fired")
+	}
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("AfterFunc not called")
+	}
+	if !fc.Now().Equal(start.Add(3500 * time.Millisecond)) {
+		t.Errorf("now %v", fc.Now())
+	}
+
+	ticker.Stop()
+	if fc.WaiterCount() != 0 {
+		t.Errorf("expected no waiters, got %d", fc.WaiterCount())
+	}
+	if timer.Stop() {
+		t.Errorf("fired timer should not be stoppable")
+	}
+}
@@ -0,0 +1,83 @@
+package GoTask
+
+import (
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/structure"
+	"time"
+)
+
+// 默认每个key保留的执行记录数
+const DefaultHistorySize = 20
+
+// 一次执行的记录
+type HistoryEntry struct {
+	Key           string
+	ScheduledTime time.Time              // 计划时刻 主动执行时为time.Time{}
+	StartTime     time.Time              // 开始执行时刻
+	EndTime       time.Time              // 执行结束时刻
+	Duration      time.Duration          // 执行时长
+	Lag           time.Duration          // 开始执行时刻与计划时刻的差值 重试时为与重试到期时刻的差值 主动执行时为0
+	Gid           pool.GoroutineUID      // 执行线程id
+	Result        map[string]interface{} // 执行结果
+	Error         error                  // 执行错误
+	Attempt       int                    // 第几次尝试 首次执行为1
+	RunID         string                 // 所属的工作流执行id 非工作流执行时为空
+}
+
+// 记录一次执行 每个key保留最近Options.HistorySize条 全局保留最近Options.GlobalHistorySize条
+func (tt *TimedTask) recordHistory(run *taskRun, gid pool.GoroutineUID, start, end time.Time, lag time.Duration, res map[string]interface{}, err error) {
+	entry := &HistoryEntry{
+		Key:           run.ti.Key,
+		ScheduledTime: run.scheduled,
+		StartTime:     start,
+		EndTime:       end,
+		Duration:      end.Sub(start),
+		Lag:           lag,
+		Gid:           gid,
+		Result:        res,
+		Error:         err,
+		Attempt:       run.attempt,
+	}
+	if run.step != nil {
+		entry.RunID = run.step.run.id
+	}
+	q, ok := tt.history.Load(entry.Key)
+	if !ok {
+		q, _ = tt.history.LoadOrStore(entry.Key, structure.NewQueue(tt.o.HistorySize))
+	}
+	q.(*structure.Queue).Push(entry)
+	if tt.globalHistory != nil {
+		tt.globalHistory.Push(entry)
+	}
+}
+
+func toHistoryEntries(list []interface{}) []*HistoryEntry {
+	entries := make([]*HistoryEntry, 0, len(list))
+	for _, v := range list {
+		entries = append(entries, v.(*HistoryEntry))
+	}
+	return entries
+}
+
+// 获取key最近的limit条执行记录 从晚到早排列 limit不大于0时返回所有保留的记录
+// 任务执行结束后记录仍会保留 任务被取消或禁止时记录一并删除 全局记录仍会保留
+func (tt *TimedTask) GetHistory(key string, limit int) []*HistoryEntry {
+	q, ok := tt.history.Load(key)
+	if !ok {
+		return make([]*HistoryEntry, 0)
+	}
+	return toHistoryEntries(q.(*structure.Queue).Latest(limit))
+}
+
+// 获取所有key最近的limit条执行记录 从晚到早排列 未开启全局记录时返回空
+func (tt *TimedTask) GetGlobalHistory(limit int) []*HistoryEntry {
+	if tt.globalHistory == nil {
+		return make([]*HistoryEntry, 0)
+	}
+	return toHistoryEntries(tt.globalHistory.Latest(limit))
+}
+
+// 清除key的执行记录及执行统计
+func (tt *TimedTask) ClearHistory(key string) {
+	tt.forget(key)
+}
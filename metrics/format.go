@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 指标类型
+const (
+	typeGauge     = "gauge"
+	typeCounter   = "counter"
+	typeHistogram = "histogram"
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+// 一个采样值 suffix为指标名后缀 如直方图的_bucket
+type sample struct {
+	suffix string
+	labels []label
+	value  float64
+}
+
+// 同名指标的所有采样值 输出时HELP及TYPE只出现一次
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// 按首次出现顺序收集指标
+type collector struct {
+	namespace string
+	families  []*family
+	index     map[string]*family
+}
+
+func newCollector(namespace string) *collector {
+	return &collector{
+		namespace: namespace,
+		families:  make([]*family, 0),
+		index:     make(map[string]*family),
+	}
+}
+
+func (c *collector) family(name, help, typ string) *family {
+	name = c.namespace + "_" + name
+	f, ok := c.index[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		c.index[name] = f
+		c.families = append(c.families, f)
+	}
+	return f
+}
+
+func (c *collector) gauge(name, help string, value float64, labels ...label) {
+	f := c.family(name, help, typeGauge)
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+func (c *collector) counter(name, help string, value float64, labels ...label) {
+	f := c.family(name, help, typeCounter)
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// 直方图 bounds为桶上界 counts为对应的累计值
+func (c *collector) histogram(name, help string, bounds, counts []float64, sum float64, count int64, labels ...label) {
+	f := c.family(name, help, typeHistogram)
+	for i, b := range bounds {
+		f.samples = append(f.samples, sample{
+			suffix: "_bucket",
+			labels: withLabel(labels, "le", formatFloat(b)),
+			value:  counts[i],
+		})
+	}
+	f.samples = append(f.samples,
+		sample{suffix: "_bucket", labels: withLabel(labels, "le", "+Inf"), value: float64(count)},
+		sample{suffix: "_sum", labels: labels, value: sum},
+		sample{suffix: "_count", labels: labels, value: float64(count)},
+	)
+}
+
+// 按Prometheus文本格式输出
+func (c *collector) writeTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, f := range c.families {
+		cw.writeString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		cw.writeString("# TYPE " + f.name + " " + f.typ + "\n")
+		for _, s := range f.samples {
+			cw.writeString(f.name + s.suffix)
+			if len(s.labels) > 0 {
+				cw.writeString("{")
+				for i, l := range s.labels {
+					if i > 0 {
+						cw.writeString(",")
+					}
+					cw.writeString(l.name + "=\"" + escapeLabel(l.value) + "\"")
+				}
+				cw.writeString("}")
+			}
+			cw.writeString(" " + formatFloat(s.value) + "\n")
+		}
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// 记录写入字节数 出错后不再写入
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) writeString(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+// 追加一个标签 不修改原切片
+func withLabel(labels []label, name, value string) []label {
+	res := make([]label, 0, len(labels)+1)
+	res = append(res, labels...)
+	return append(res, label{name: name, value: value})
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
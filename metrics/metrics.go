@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"bytes"
+	"gitee.com/magicianlyx/GoTask"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/task"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 默认指标名前缀
+const DefaultNamespace = "gotask"
+
+// Prometheus文本格式的Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type namedPool struct {
+	name string
+	p    *pool.GoroutinePool
+}
+
+type namedTimedTask struct {
+	name string
+	tt   *GoTask.TimedTask
+}
+
+// 将线程池及定时任务组件的状态以Prometheus文本格式导出
+// 每次抓取时读取各组件的当前值 不需要运行Prometheus客户端
+type Exporter struct {
+	l          sync.RWMutex
+	namespace  string
+	pools      []*namedPool
+	timedTasks []*namedTimedTask
+}
+
+// namespace为指标名前缀 为空时使用DefaultNamespace
+func NewExporter(namespace string) *Exporter {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return &Exporter{
+		l:          sync.RWMutex{},
+		namespace:  namespace,
+		pools:      make([]*namedPool, 0),
+		timedTasks: make([]*namedTimedTask, 0),
+	}
+}
+
+// 注册线程池 name作为指标的pool标签 返回自身
+func (e *Exporter) RegisterPool(name string, p *pool.GoroutinePool) *Exporter {
+	e.l.Lock()
+	defer e.l.Unlock()
+	e.pools = append(e.pools, &namedPool{name: name, p: p})
+	return e
+}
+
+// 注册定时任务组件 name作为指标的scheduler标签 返回自身
+func (e *Exporter) RegisterTimedTask(name string, tt *GoTask.TimedTask) *Exporter {
+	e.l.Lock()
+	defer e.l.Unlock()
+	e.timedTasks = append(e.timedTasks, &namedTimedTask{name: name, tt: tt})
+	return e
+}
+
+// 按Prometheus文本格式输出所有已注册组件的指标
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	e.l.RLock()
+	c := newCollector(e.namespace)
+	for _, np := range e.pools {
+		collectPool(c, np)
+	}
+	for _, nt := range e.timedTasks {
+		collectTimedTask(c, nt)
+	}
+	e.l.RUnlock()
+	return c.writeTo(w)
+}
+
+// 实现http.Handler
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	buf := &bytes.Buffer{}
+	if _, err := e.WriteTo(buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(buf.Bytes())
+}
+
+func collectPool(c *collector, np *namedPool) {
+	p := np.p
+	l := label{name: "pool", value: np.name}
+	c.gauge("pool_active_goroutines", "Number of goroutines currently running a task.", float64(p.GetCurrentActiveCount()), l)
+	c.gauge("pool_goroutines", "Number of live goroutines.", float64(p.GetGoroutineCount()), l)
+	c.gauge("pool_goroutines_peak", "Peak number of live goroutines.", float64(p.GetGoroutinePeak()), l)
+	c.gauge("pool_queued_tasks", "Number of tasks waiting for a goroutine.", float64(p.GetWorkCount()), l)
+	for priority, n := range p.GetWorkCountByPriority() {
+		c.gauge("pool_queued_tasks_by_priority", "Number of tasks waiting for a goroutine by priority.", float64(n),
+			l, label{name: "priority", value: strconv.Itoa(priority)})
+	}
+	c.counter("pool_rejected_tasks_total", "Number of tasks rejected by the pool.", float64(p.GetRejectedCount()), l)
+
+	settle := p.GetStatusSettle()
+	statuses := make([]pool.GoroutineStatus, 0, len(settle))
+	for s := range settle {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i] < statuses[j]
+	})
+	for _, s := range statuses {
+		c.counter("pool_status_seconds_total", "Total time goroutines spent in each status.", settle[s].Seconds(),
+			l, label{name: "status", value: s.ToString()})
+	}
+}
+
+func collectTimedTask(c *collector, nt *namedTimedTask) {
+	tt := nt.tt
+	l := label{name: "scheduler", value: nt.name}
+
+	infos := tt.GetTimedTaskInfo()
+	paused := 0
+	for _, ti := range infos {
+		if ti.Paused {
+			paused++
+		}
+	}
+	c.gauge("scheduler_tasks", "Number of registered tasks.", float64(len(infos)), l)
+	c.gauge("scheduler_paused_tasks", "Number of paused tasks.", float64(paused), l)
+	c.gauge("scheduler_banned_keys", "Number of banned keys.", float64(tt.GetBanCount()), l)
+	pausedAll := 0.0
+	if _, ok := tt.IsPausedAll(); ok {
+		pausedAll = 1
+	}
+	c.gauge("scheduler_paused", "Whether the whole scheduler is paused.", pausedAll, l)
+
+	depth := tt.GetQueueDepth()
+	priorities := make([]task.Priority, 0, len(depth))
+	for p := range depth {
+		priorities = append(priorities, p)
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i] > priorities[j]
+	})
+	for _, p := range priorities {
+		c.gauge("scheduler_queued_runs", "Number of runs waiting for an executor by priority.", float64(depth[p]),
+			l, label{name: "priority", value: priorityLabel(p)})
+	}
+
+	counters := tt.GetCounters()
+	c.counter("scheduler_fires_total", "Number of scheduled fires.", float64(counters.Fires), l)
+	c.counter("scheduler_runs_total", "Number of task runs including retries.", float64(counters.Runs), l)
+	c.counter("scheduler_failures_total", "Number of failed task runs.", float64(counters.Failures), l)
+	c.counter("scheduler_bans_total", "Number of keys banned.", float64(counters.Bans), l)
+	c.counter("scheduler_misfires_total", "Number of fires dropped by misfire policies.", float64(counters.Misfires), l)
+
+	all := tt.GetAllRunStats()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := all[key]
+		kl := []label{l, {name: "key", value: key}}
+		c.counter("task_runs_total", "Number of runs of a task including retries.", float64(s.Count), kl...)
+		c.counter("task_failures_total", "Number of failed runs of a task.", float64(s.Failures), kl...)
+		h := s.Histogram
+		bounds := make([]float64, len(h.Buckets))
+		counts := make([]float64, len(h.Counts))
+		for i, b := range h.Buckets {
+			bounds[i] = b.Seconds()
+			counts[i] = float64(h.Counts[i])
+		}
+		c.histogram("task_duration_seconds", "Run duration of a task.", bounds, counts, h.Sum.Seconds(), h.Count, kl...)
+		lagStats(c, kl, s.Lag)
+	}
+}
+
+// 最近执行的延迟统计
+func lagStats(c *collector, labels []label, s GoTask.DurationSummary) {
+	stats := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"min", s.Min},
+		{"max", s.Max},
+		{"avg", s.Avg},
+		{"p95", s.P95},
+		{"p99", s.P99},
+	}
+	for _, st := range stats {
+		c.gauge("task_lag_seconds", "Delay between scheduled and actual start over recent runs.", st.d.Seconds(),
+			withLabel(labels, "stat", st.name)...)
+	}
+}
+
+func priorityLabel(p task.Priority) string {
+	if p.IsValid() {
+		return p.ToString()
+	}
+	return strconv.Itoa(int(p))
+}
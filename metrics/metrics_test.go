@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"gitee.com/magicianlyx/GoTask"
+	"gitee.com/magicianlyx/GoTask/clock"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/task"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector_WriteTo(t *testing.T) {
+	c := newCollector("ns")
+	l := label{name: "key", value: "a\"b\\c\nd"}
+	c.gauge("up", "Up.\nSecond line", 1, l)
+	c.gauge("up", "Up.\nSecond line", 0.5)
+	c.histogram("latency_seconds", "Latency.", []float64{0.1, 1}, []float64{1, 2}, 1.5, 3)
+	buf := &bytes.Buffer{}
+	if _, err := c.writeTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP ns_up Up.\nSecond line
+# TYPE ns_up gauge
+ns_up{key="a\"b\\c\nd"} 1
+ns_up 0.5
+# HELP ns_latency_seconds Latency.
+# TYPE ns_latency_seconds histogram
+ns_latency_seconds_bucket{le="0.1"} 1
+ns_latency_seconds_bucket{le="1"} 2
+ns_latency_seconds_bucket{le="+Inf"} 3
+ns_latency_seconds_sum 1.5
+ns_latency_seconds_count 3
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestExporter_ServeHTTP(t *testing.T) {
+	p := pool.NewGoroutinePool(&pool.Options{})
+	defer p.Stop()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	fc := clock.NewFakeClock(start)
+	tt := GoTask.NewTimedTaskWithOptions(&GoTask.Options{
+		RoutineCount:    1,
+		DurationBuckets: []time.Duration{time.Hour},
+		Clock:           fc,
+	})
+	defer tt.Stop()
+	done := make(chan struct{}, 1)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		done <- struct{}{}
+	})
+	tt.Add("job", func() (map[string]interface{}, error) {
+		return nil, errors.New("fail")
+	}, task.NewSpecSchedule(time.Minute))
+	fc.BlockUntil(1)
+	fc.Set(start.Add(time.Minute))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task not executed")
+	}
+	tt.Ban("banned")
+
+	e := NewExporter("").RegisterPool("workers", p).RegisterTimedTask("main", tt)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE gotask_pool_goroutines gauge",
+		`gotask_pool_rejected_tasks_total{pool="workers"} 0`,
+		`gotask_scheduler_fires_total{scheduler="main"} 1`,
+		`gotask_scheduler_failures_total{scheduler="main"} 1`,
+		`gotask_scheduler_bans_total{scheduler="main"} 1`,
+		`gotask_scheduler_banned_keys{scheduler="main"} 1`,
+		`gotask_task_runs_total{scheduler="main",key="job"} 1`,
+		`gotask_task_duration_seconds_bucket{scheduler="main",key="job",le="3600"} 1`,
+		`gotask_task_duration_seconds_count{scheduler="main",key="job"} 1`,
+		"# TYPE gotask_task_duration_seconds histogram",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, body)
+		}
+	}
+	if strings.Count(body, "# TYPE gotask_task_lag_seconds ") != 1 {
+		t.Errorf("lag family should be declared once")
+	}
+}
@@ -0,0 +1,114 @@
+package GoTask
+
+import (
+	"gitee.com/magicianlyx/GoTask/clock"
+	"gitee.com/magicianlyx/GoTask/store"
+	"gitee.com/magicianlyx/GoTask/task"
+	"gitee.com/magicianlyx/GoTask/trace"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// 定时任务组件配置
+type Options struct {
+	RoutineCount int         // 执行任务的线程数
+	Clock        clock.Clock // 时钟 为空时使用系统时钟
+
+	PriorityAging time.Duration // 优先级老化时长 任务每排队等待该时长相当于提升一个优先级 默认DefaultPriorityAging
+
+	HistorySize       int // 每个key保留的执行记录数 默认DefaultHistorySize
+	GlobalHistorySize int // 全局保留的执行记录数 为0时不记录全局执行记录
+	StatsWindow       int // 每个key参与执行时长统计的最近执行次数 默认DefaultStatsWindow
+
+	DurationBuckets []time.Duration // 执行时长直方图的桶上界 升序 默认DefaultDurationBuckets
+
+	Store             store.JobStore // 任务存储 非空时通过名称添加的任务及禁止列表会被持久化 并在创建时恢复
+	Registry          *task.Registry // 任务方法注册表 通过名称添加任务及恢复任务时使用
+	StoreErrorHandler func(error)    // 任务存储出错时的处理方法
+
+	CatchUp task.PausePolicy // 调度器全局恢复时 暂停期间到期的调度的处理策略
+
+	Tracer trace.Tracer // 埋点 为空时不记录
+}
+
+// 构建默认配置
+func NewDefaultOptions() *Options {
+	return &Options{
+		RoutineCount: runtime.NumCPU(),
+		Clock:        clock.NewRealClock(),
+		CatchUp:      task.PauseSkip,
+
+		PriorityAging: DefaultPriorityAging,
+
+		HistorySize: DefaultHistorySize,
+		StatsWindow: DefaultStatsWindow,
+
+		DurationBuckets: DefaultDurationBuckets,
+
+		Tracer: trace.NewNoopTracer(),
+	}
+}
+
+// 填充参数
+func (o *Options) fillDefaultOptions() {
+	oDefault := NewDefaultOptions()
+	if o.RoutineCount <= 0 {
+		o.RoutineCount = oDefault.RoutineCount
+	}
+	if o.Clock == nil {
+		o.Clock = oDefault.Clock
+	}
+	if o.PriorityAging <= 0 {
+		o.PriorityAging = oDefault.PriorityAging
+	}
+	if o.HistorySize <= 0 {
+		o.HistorySize = oDefault.HistorySize
+	}
+	if o.StatsWindow <= 0 {
+		o.StatsWindow = oDefault.StatsWindow
+	}
+	if len(o.DurationBuckets) == 0 {
+		o.DurationBuckets = oDefault.DurationBuckets
+	} else {
+		o.DurationBuckets = append([]time.Duration{}, o.DurationBuckets...)
+		sort.Slice(o.DurationBuckets, func(i, j int) bool {
+			return o.DurationBuckets[i] < o.DurationBuckets[j]
+		})
+	}
+	if o.GlobalHistorySize < 0 {
+		o.GlobalHistorySize = 0
+	}
+	if !o.CatchUp.IsValid() {
+		o.CatchUp = oDefault.CatchUp
+	}
+	if o.Tracer == nil {
+		o.Tracer = oDefault.Tracer
+	}
+}
+
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return &Options{}
+	}
+	return &Options{
+		RoutineCount: o.RoutineCount,
+		Clock:        o.Clock,
+
+		PriorityAging: o.PriorityAging,
+
+		HistorySize:       o.HistorySize,
+		GlobalHistorySize: o.GlobalHistorySize,
+		StatsWindow:       o.StatsWindow,
+
+		DurationBuckets: append([]time.Duration{}, o.DurationBuckets...),
+
+		Store:             o.Store,
+		Registry:          o.Registry,
+		StoreErrorHandler: o.StoreErrorHandler,
+
+		CatchUp: o.CatchUp,
+
+		Tracer: o.Tracer,
+	}
+}
@@ -0,0 +1,100 @@
+package GoTask
+
+import (
+	"gitee.com/magicianlyx/GoTask/task"
+	"sync"
+)
+
+// 单个key的执行状态
+type runState struct {
+	running int      // 已派发且未结束的执行数
+	pending *taskRun // 排队等待的执行
+}
+
+// 按任务的并发执行策略控制同一key的派发（线程安全）
+type runGuard struct {
+	l sync.Mutex
+	m map[string]*runState
+}
+
+func newRunGuard() *runGuard {
+	return &runGuard{
+		l: sync.Mutex{},
+		m: make(map[string]*runState),
+	}
+}
+
+func (g *runGuard) getOrCreate(key string) *runState {
+	if s, ok := g.m[key]; ok {
+		return s
+	}
+	s := &runState{}
+	g.m[key] = s
+	return s
+}
+
+// 尝试开始一次执行 返回是否可以立即派发及是否被跳过
+func (g *runGuard) acquire(run *taskRun) (dispatch bool, skipped bool) {
+	g.l.Lock()
+	defer g.l.Unlock()
+	s := g.getOrCreate(run.ti.Key)
+	if s.running == 0 {
+		s.running++
+		return true, false
+	}
+	switch run.ti.GetOptions().Overlap {
+	case task.OverlapSkip:
+		return false, true
+	case task.OverlapQueue:
+		if s.pending != nil {
+			return false, true
+		}
+		s.pending = run
+		return false, false
+	default:
+		s.running++
+		return true, false
+	}
+}
+
+// 结束一次执行 有排队等待的执行时将其返回 由调用方派发
+func (g *runGuard) release(key string) *taskRun {
+	g.l.Lock()
+	defer g.l.Unlock()
+	s, ok := g.m[key]
+	if !ok {
+		return nil
+	}
+	s.running--
+	if s.pending != nil {
+		run := s.pending
+		s.pending = nil
+		s.running++
+		return run
+	}
+	if s.running <= 0 {
+		delete(g.m, key)
+	}
+	return nil
+}
+
+// 是否正在执行
+func (g *runGuard) isRunning(key string) bool {
+	g.l.Lock()
+	defer g.l.Unlock()
+	s, ok := g.m[key]
+	return ok && s.running > 0
+}
+
+// 获取所有排队等待的执行
+func (g *runGuard) pendingRuns() []*taskRun {
+	g.l.Lock()
+	defer g.l.Unlock()
+	runs := make([]*taskRun, 0)
+	for _, s := range g.m {
+		if s.pending != nil {
+			runs = append(runs, s.pending)
+		}
+	}
+	return runs
+}
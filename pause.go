@@ -0,0 +1,156 @@
+package GoTask
+
+import (
+	"gitee.com/magicianlyx/GoTask/task"
+	"sync/atomic"
+	"time"
+)
+
+func (tt *TimedTask) pause(key string) error {
+	err := ErrTaskIsNotExist
+	tt.tMap.Update(key, func(t *task.TaskInfo) {
+		if t.Paused {
+			err = ErrTaskIsPaused
+			return
+		}
+		t.Pause(tt.clock.Now())
+		err = nil
+	})
+	if err == nil {
+		tt.reSelectAfterUpdate()
+	}
+	return err
+}
+
+// 返回操作后的任务信息副本 任务不存在时为nil
+func (tt *TimedTask) pauseWithCb(key string, cb bool) (*task.TaskInfo, error) {
+	tt.l.Lock()
+	err := tt.pause(key)
+	res := tt.tMap.Get(key)
+	tt.l.Unlock()
+	if err == nil {
+		tt.persistJob(key)
+	}
+	if cb {
+		tt.invokePauseCallback(key, err)
+	}
+	return res, err
+}
+
+// 暂停定时任务 暂停期间不会触发调度 正在执行及等待重试的执行不受影响
+// 任务的执行次数、添加时间及调度计划保持不变 可通过Resume恢复
+func (tt *TimedTask) Pause(key string) {
+	tt.pauseWithCb(key, true)
+}
+
+func (tt *TimedTask) resume(key string) error {
+	err, dropped := ErrTaskIsNotExist, 0
+	nti, _ := tt.tMap.Update(key, func(t *task.TaskInfo) {
+		if !t.Paused {
+			err = ErrTaskIsUnPaused
+			return
+		}
+		dropped = t.Resume(tt.clock.Now())
+		err = nil
+	})
+	if err != nil {
+		return err
+	}
+	tt.afterCatchUp(nti, dropped)
+	tt.reSelectAfterUpdate()
+	return nil
+}
+
+// 丢弃错过的调度后通知回调 没有下一次执行计划时清除任务
+func (tt *TimedTask) afterCatchUp(ti *task.TaskInfo, dropped int) {
+	if dropped > 0 {
+		tt.invokeMisfireCallback(ti.Clone(), dropped, false)
+	}
+	if !ti.HasNextExecute() {
+		tt.remove(ti.Key)
+	}
+}
+
+// 返回操作后的任务信息副本 任务不存在时为nil
+func (tt *TimedTask) resumeWithCb(key string, cb bool) (*task.TaskInfo, error) {
+	tt.l.Lock()
+	err := tt.resume(key)
+	res := tt.tMap.Get(key)
+	tt.l.Unlock()
+	if err == nil {
+		tt.persistJob(key)
+	}
+	if cb {
+		tt.invokeResumeCallback(key, err)
+	}
+	return res, err
+}
+
+// 恢复被暂停的定时任务 暂停期间错过的调度按任务配置的暂停策略处理
+func (tt *TimedTask) Resume(key string) {
+	tt.resumeWithCb(key, true)
+}
+
+// 任务是否已暂停
+func (tt *TimedTask) IsPaused(key string) bool {
+	ti := tt.tMap.Get(key)
+	return ti != nil && ti.Paused
+}
+
+// 调度器是否已全局暂停
+func (tt *TimedTask) isPausedAll() bool {
+	return atomic.LoadInt64(&tt.pausedAll) == 1
+}
+
+// 全局暂停调度器 暂停期间不再触发任何调度 执行线程不受影响
+// 正在执行、等待重试及通过Execute主动执行的任务仍会被执行 重复调用无效
+func (tt *TimedTask) PauseAll() {
+	tt.l.Lock()
+	defer tt.l.Unlock()
+	if !atomic.CompareAndSwapInt64(&tt.pausedAll, 0, 1) {
+		return
+	}
+	tt.pausedAllTime = tt.clock.Now()
+	tt.reSelectAfterUpdate()
+}
+
+// 恢复全局暂停的调度器 暂停期间到期的调度按Options.CatchUp策略处理 重复调用无效
+// 被单独暂停的任务仍保持暂停
+func (tt *TimedTask) ResumeAll() {
+	tt.l.Lock()
+	if !tt.isPausedAll() {
+		tt.l.Unlock()
+		return
+	}
+	changed := make([]string, 0)
+	now := tt.clock.Now()
+	for key := range tt.tMap.GetAll() {
+		dropped := 0
+		nti, ok := tt.tMap.Update(key, func(t *task.TaskInfo) {
+			if t.Paused {
+				return
+			}
+			dropped = t.CatchUp(now, tt.o.CatchUp)
+		})
+		if ok {
+			tt.afterCatchUp(nti, dropped)
+		}
+		if dropped > 0 || !tt.tMap.IsExist(key) {
+			changed = append(changed, key)
+		}
+	}
+	tt.pausedAllTime = time.Time{}
+	atomic.StoreInt64(&tt.pausedAll, 0)
+	tt.reSelectAfterUpdate()
+	tt.l.Unlock()
+	for _, key := range changed {
+		tt.persistJob(key)
+	}
+}
+
+// 调度器是否已全局暂停 及暂停的时间
+func (tt *TimedTask) IsPausedAll() (time.Time, bool) {
+	tt.l.RLock()
+	defer tt.l.RUnlock()
+	return tt.pausedAllTime, tt.isPausedAll()
+}
@@ -0,0 +1,137 @@
+package GoTask
+
+import (
+	"fmt"
+	"gitee.com/magicianlyx/GoTask/store"
+	"gitee.com/magicianlyx/GoTask/task"
+)
+
+// 通过注册表中的任务方法名称添加定时任务 配置了任务存储时任务会被持久化
+func (tt *TimedTask) AddByName(key string, name string, sche task.ISchedule, options ...*task.Options) {
+	tt.addByNameWithCb(key, name, sche, true, options...)
+}
+
+// 通过注册表中的任务方法名称添加或修改定时任务
+func (tt *TimedTask) SetByName(key string, name string, sche task.ISchedule, options ...*task.Options) {
+	tt.setByNameWithCb(key, name, sche, true, options...)
+}
+
+func (tt *TimedTask) addByNameWithCb(key string, name string, sche task.ISchedule, cb bool, options ...*task.Options) (*task.TaskInfo, error) {
+	info, err := tt.newNamedTaskInfo(key, name, sche, options...)
+	if err != nil {
+		if cb {
+			tt.invokeAddCallback(info, err)
+		}
+		return nil, err
+	}
+	return tt.addWithCb(info, cb)
+}
+
+func (tt *TimedTask) setByNameWithCb(key string, name string, sche task.ISchedule, cb bool, options ...*task.Options) (*task.TaskInfo, error) {
+	info, err := tt.newNamedTaskInfo(key, name, sche, options...)
+	if err != nil {
+		if cb {
+			tt.invokeAddCallback(info, err)
+		}
+		return nil, err
+	}
+	return tt.setWithCb(info, cb)
+}
+
+func (tt *TimedTask) newNamedTaskInfo(key string, name string, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	if tt.o.Registry == nil {
+		return task.NewTaskInfo(key, nil, sche), task.ErrTaskNameNotRegistered
+	}
+	info, err := tt.o.Registry.NewTaskInfo(key, name, sche)
+	if err != nil {
+		return task.NewTaskInfo(key, nil, sche), err
+	}
+	return info.WithClock(tt.clock).WithOptions(options...), nil
+}
+
+// 从任务存储中恢复任务及禁止列表 未配置注册表或注册表中不存在的任务方法会被跳过并通知存储错误
+func (tt *TimedTask) restore() {
+	if tt.o.Store == nil {
+		return
+	}
+	jobs, bans, err := tt.o.Store.Load()
+	if err != nil {
+		tt.storeError(err)
+		return
+	}
+	for _, key := range bans {
+		tt.bMap.Add(key)
+	}
+	for _, rec := range jobs {
+		tt.persisted.Add(rec.Key)
+		if tt.o.Registry == nil {
+			tt.storeError(fmt.Errorf("%w: `%s` of `%s`", task.ErrTaskNameNotRegistered, rec.Name, rec.Key))
+			continue
+		}
+		ti, err := rec.Restore(tt.o.Registry)
+		if err != nil {
+			tt.storeError(err)
+			continue
+		}
+		ti.SetClock(tt.clock)
+		tt.newKeyContext(ti.Key)
+		tt.tMap.AddOrSet(ti.Key, ti)
+	}
+}
+
+// 持久化key当前的任务信息 任务已被清除或替换为未命名的任务时删除原有的持久化记录
+// 不能在调度器锁内或任务字典的修改回调中调用 防止磁盘读写阻塞调度
+// 持久化串行执行且总是写入最新的任务信息 保证并发修改时存储中不会留下旧的状态
+func (tt *TimedTask) persistJob(key string) {
+	if tt.o.Store == nil {
+		return
+	}
+	tt.persistL.Lock()
+	defer tt.persistL.Unlock()
+	ti := tt.tMap.Get(key)
+	if ti == nil || ti.Name == "" {
+		if !tt.persisted.IsExist(key) {
+			return
+		}
+		if err := tt.o.Store.Delete(key); err != nil {
+			tt.storeError(err)
+			return
+		}
+		tt.persisted.Delete(key)
+		return
+	}
+	rec, err := store.NewJobRecord(ti)
+	if err != nil {
+		tt.storeError(err)
+		return
+	}
+	if err := tt.o.Store.Save(rec); err != nil {
+		tt.storeError(err)
+		return
+	}
+	tt.persisted.Add(key)
+}
+
+// 持久化key当前的禁止状态 与persistJob相同 不能在调度器锁内调用
+func (tt *TimedTask) persistBan(key string) {
+	if tt.o.Store == nil {
+		return
+	}
+	tt.persistL.Lock()
+	defer tt.persistL.Unlock()
+	var err error
+	if tt.isBan(key) {
+		err = tt.o.Store.SaveBan(key)
+	} else {
+		err = tt.o.Store.DeleteBan(key)
+	}
+	if err != nil {
+		tt.storeError(err)
+	}
+}
+
+func (tt *TimedTask) storeError(err error) {
+	if tt.o.StoreErrorHandler != nil {
+		tt.o.StoreErrorHandler(err)
+	}
+}
@@ -0,0 +1,161 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var (
+	ErrFutureCancelled = errors.New("future is cancelled")
+	ErrFutureNotDone   = errors.New("future is not done")
+	ErrNoFutures       = errors.New("no futures to wait")
+)
+
+// 有返回值的任务函数 Future被取消时ctx会被取消
+type FutureFunc func(ctx context.Context) (interface{}, error)
+
+// 通过Submit提交的任务的结果句柄
+type Future struct {
+	done   chan struct{}
+	once   sync.Once
+	value  interface{}
+	err    error
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ctx结束时Future的ctx同样被取消
+func newFuture(ctx context.Context) *Future {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Future{
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// 设置结果 只有第一次设置生效 返回是否生效
+func (f *Future) complete(value interface{}, err error) bool {
+	ok := false
+	f.once.Do(func() {
+		f.value, f.err = value, err
+		f.cancel()
+		close(f.done)
+		ok = true
+	})
+	return ok
+}
+
+// 任务结束（完成、失败或被取消）时关闭的通道
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// 等待任务结束并返回结果 ctx先结束时返回ctx的错误 不影响任务执行
+func (f *Future) Wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// 获取任务结果 不阻塞 任务未结束时返回ErrFutureNotDone
+func (f *Future) Result() (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	default:
+		return nil, ErrFutureNotDone
+	}
+}
+
+// 取消任务 未开始的任务将不再执行 执行中的任务ctx被取消 结果为ErrFutureCancelled
+// 返回是否取消成功 任务已结束时返回false
+func (f *Future) Cancel() bool {
+	return f.complete(nil, ErrFutureCancelled)
+}
+
+// 提交一个最低优先级的有返回值任务
+func (g *GoroutinePool) Submit(fn FutureFunc) *Future {
+	return g.SubmitWithPriority(fn, 0)
+}
+
+// 提交一个指定优先级的有返回值任务 任务panic时结果为*PanicError
+// 组件已关闭时结果为ErrPoolIsClosed 任务被拒绝时按拒绝策略处理 结果为对应的错误
+func (g *GoroutinePool) SubmitWithPriority(fn FutureFunc, priority int) *Future {
+	return g.SubmitWithContextPriority(context.Background(), fn, priority)
+}
+
+// 以ctx提交一个最低优先级的有返回值任务
+func (g *GoroutinePool) SubmitWithContext(ctx context.Context, fn FutureFunc) *Future {
+	return g.SubmitWithContextPriority(ctx, fn, 0)
+}
+
+// 以ctx提交一个指定优先级的有返回值任务 ctx结束时任务被取消 结果为ErrFutureCancelled
+// 执行跨度以ctx中的跨度为上游 传递给fn的ctx携带执行跨度
+func (g *GoroutinePool) SubmitWithContextPriority(ctx context.Context, fn FutureFunc, priority int) *Future {
+	f := newFuture(ctx)
+	obj := func(ctx context.Context, gid GoroutineUID) {
+		if f.ctx.Err() != nil {
+			// 执行前已被取消或提交时的ctx已结束
+			f.complete(nil, ErrFutureCancelled)
+			return
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				f.complete(nil, NewPanicError(r))
+			}
+		}()
+		f.complete(fn(ctx))
+	}
+	discard := func(err error) {
+		if f.ctx.Err() != nil {
+			// 等待任务位时被取消
+			err = ErrFutureCancelled
+		}
+		f.complete(nil, err)
+	}
+	g.put(f.ctx, obj, discard, priority, true)
+	return f
+}
+
+// 等待所有任务结束 返回与futures顺序一致的结果及第一个失败任务的错误
+// ctx先结束时返回ctx的错误
+func WaitAll(ctx context.Context, futures ...*Future) ([]interface{}, error) {
+	values := make([]interface{}, len(futures))
+	var first error
+	for i, f := range futures {
+		v, err := f.Wait(ctx)
+		if err != nil && ctx.Err() != nil {
+			return values, ctx.Err()
+		}
+		values[i] = v
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return values, first
+}
+
+// 等待任一任务结束 返回该任务的下标及结果 ctx先结束时下标为-1并返回ctx的错误
+// futures为空时下标为-1并立即返回ErrNoFutures
+func WaitAny(ctx context.Context, futures ...*Future) (int, interface{}, error) {
+	if len(futures) == 0 {
+		return -1, nil, ErrNoFutures
+	}
+	cases := make([]reflect.SelectCase, 0, len(futures)+1)
+	for _, f := range futures {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.Done())})
+	}
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	i, _, _ := reflect.Select(cases)
+	if i == len(futures) {
+		return -1, nil, ctx.Err()
+	}
+	v, err := futures[i].Result()
+	return i, v, err
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGoroutinePool_Submit(t *testing.T) {
+	pool := NewGoroutinePool(&Options{GoroutineLimit: 2})
+	defer pool.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	fail := errors.New("fail")
+	futures := []*Future{
+		pool.Submit(func(ctx context.Context) (interface{}, error) {
+			return 1, nil
+		}),
+		pool.Submit(func(ctx context.Context) (interface{}, error) {
+			return nil, fail
+		}),
+		pool.Submit(func(ctx context.Context) (interface{}, error) {
+			panic("boom")
+		}),
+	}
+	values, err := WaitAll(ctx, futures...)
+	if err != fail || values[0] != 1 {
+		t.Errorf("unexpected wait all result: %v %v", values, err)
+	}
+	if _, err := futures[2].Result(); err == nil {
+		t.Errorf("panic should be reported")
+	} else if _, ok := err.(*PanicError); !ok {
+		t.Errorf("expected panic error, got %v", err)
+	}
+
+	slow := pool.Submit(func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	fast := pool.Submit(func(ctx context.Context) (interface{}, error) {
+		return "fast", nil
+	})
+	if i, v, err := WaitAny(ctx, slow, fast); i != 1 || v != "fast" || err != nil {
+		t.Errorf("unexpected wait any result: %d %v %v", i, v, err)
+	}
+	if i, v, err := WaitAny(context.Background()); i != -1 || v != nil || err != ErrNoFutures {
+		t.Errorf("wait any without futures: %d %v %v", i, v, err)
+	}
+	if _, err := slow.Result(); err != ErrFutureNotDone {
+		t.Errorf("slow future should not be done: %v", err)
+	}
+	short, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := slow.Wait(short); err != context.DeadlineExceeded {
+		t.Errorf("wait should time out: %v", err)
+	}
+	if !slow.Cancel() || slow.Cancel() {
+		t.Errorf("cancel should succeed once")
+	}
+	if _, err := slow.Wait(ctx); err != ErrFutureCancelled {
+		t.Errorf("cancelled future: %v", err)
+	}
+
+	pool.Stop()
+	if _, err := pool.Submit(func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}).Wait(ctx); err != ErrPoolIsClosed {
+		t.Errorf("submit after stop: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"gitee.com/magicianlyx/GoTask/clock"
 	"math"
 	"sync"
 	"sync/atomic"
@@ -14,17 +15,23 @@ type GoroutineSettle struct {
 	m          *LatencyMap   // 各个状态汇总记录 map[GoroutineStatus]*Latency
 	lr         *RecentRecord // 最近记录
 	createTime time.Time     // 线程创建时间
+	c          clock.Clock   // 时钟
 }
 
 func NewGoroutineSettle(d time.Duration) *GoroutineSettle {
+	return NewGoroutineSettleWithClock(d, clock.NewRealClock())
+}
+
+func NewGoroutineSettleWithClock(d time.Duration, c clock.Clock) *GoroutineSettle {
 	s := atomic.Value{}
 	s.Store(GoroutineStatusNone)
 	return &GoroutineSettle{
 		l:          sync.RWMutex{},
 		s:          s,
-		m:          NewLatencyMap(),
-		lr:         NewRecentRecord(d),
-		createTime: time.Now(),
+		m:          NewLatencyMapWithClock(c),
+		lr:         NewRecentRecordWithClock(d, c),
+		createTime: c.Now(),
+		c:          c,
 	}
 }
 
@@ -38,7 +45,7 @@ func (g *GoroutineSettle) setStatus(s GoroutineStatus) {
 
 // 获取线程存活时间
 func (g *GoroutineSettle) getSurvivalDuration() time.Duration {
-	return time.Now().Sub(g.createTime)
+	return g.c.Since(g.createTime)
 }
 
 func (g *GoroutineSettle) GetSurvivalDuration() time.Duration {
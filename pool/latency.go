@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"gitee.com/magicianlyx/GoTask/clock"
 	"sync"
 	"time"
 )
@@ -11,14 +12,20 @@ type Latency struct {
 	status GoroutineStatus // 状态
 	amount time.Duration   // 总时长
 	last   time.Time       // 最后一次启动时刻
+	c      clock.Clock     // 时钟
 }
 
 func NewLatency(status GoroutineStatus) *Latency {
+	return NewLatencyWithClock(status, clock.NewRealClock())
+}
+
+func NewLatencyWithClock(status GoroutineStatus, c clock.Clock) *Latency {
 	return &Latency{
 		l:      sync.RWMutex{},
 		status: status,
 		amount: 0,
 		last:   time.Time{},
+		c:      c,
 	}
 }
 
@@ -28,6 +35,7 @@ func (l *Latency) clone() *Latency {
 		status: l.status,
 		amount: l.amount,
 		last:   l.last,
+		c:      l.c,
 	}
 }
 
@@ -52,7 +60,7 @@ func (l *Latency) Start() {
 	l.l.Lock()
 	defer l.l.Unlock()
 	if !l.isStart() {
-		l.last = time.Now()
+		l.last = l.c.Now()
 	}
 }
 
@@ -61,7 +69,7 @@ func (l *Latency) Stop() {
 	l.l.Lock()
 	defer l.l.Unlock()
 	if l.isStart() {
-		now := time.Now()
+		now := l.c.Now()
 		latency := now.Sub(l.last)
 		l.amount += latency
 		l.last = time.Time{}
@@ -79,6 +87,7 @@ func (l *Latency) amountDurationOfTime(t time.Time) *Latency {
 		status: l.status,
 		amount: l.amount + latency,
 		last:   l.last,
+		c:      l.c,
 	}
 }
 
@@ -91,5 +100,5 @@ func (l *Latency) AmountDurationOfTime(t time.Time) *Latency {
 func (l *Latency) AmountDurationOfNow() *Latency {
 	l.l.RLock()
 	defer l.l.RUnlock()
-	return l.amountDurationOfTime(time.Now())
+	return l.amountDurationOfTime(l.c.Now())
 }
@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"gitee.com/magicianlyx/GoTask/clock"
 	"sync"
 	"time"
 )
@@ -9,12 +10,18 @@ import (
 type LatencyMap struct {
 	l sync.RWMutex
 	m map[GoroutineStatus]*Latency
+	c clock.Clock
 }
 
 func NewLatencyMap() *LatencyMap {
+	return NewLatencyMapWithClock(clock.NewRealClock())
+}
+
+func NewLatencyMapWithClock(c clock.Clock) *LatencyMap {
 	return &LatencyMap{
 		sync.RWMutex{},
 		make(map[GoroutineStatus]*Latency),
+		c,
 	}
 }
 
@@ -22,7 +29,7 @@ func (g *LatencyMap) getOrCreate(status GoroutineStatus) *Latency {
 	if v, ok := g.m[status]; ok {
 		return v
 	} else {
-		l := NewLatency(status)
+		l := NewLatencyWithClock(status, g.c)
 		g.m[status] = l
 		return l
 	}
@@ -56,7 +63,7 @@ func (g *LatencyMap) GetAll() map[GoroutineStatus]time.Duration {
 func (g *LatencyMap) Clone() *LatencyMap {
 	g.l.RLock()
 	defer g.l.RUnlock()
-	r := NewLatencyMap()
+	r := NewLatencyMapWithClock(g.c)
 	for status := range g.m {
 		latency := g.m[status]
 		latency = latency.AmountDurationOfNow()
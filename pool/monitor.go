@@ -62,7 +62,7 @@ func (m *DynamicPoolMonitor) GetCurrentActiveCount() int {
 func (m *DynamicPoolMonitor) construct() GoroutineUID {
 	gid := m.k.Generate()
 	m.c.Inc()
-	m.g.NewGoroutineSettle(gid, NewGoroutineSettle(m.o.AutoMonitorDuration))
+	m.g.NewGoroutineSettle(gid, NewGoroutineSettleWithClock(m.o.AutoMonitorDuration, m.o.Clock))
 	return gid
 }
 
@@ -1,16 +1,25 @@
 package pool
 
 import (
+	"gitee.com/magicianlyx/GoTask/clock"
+	"gitee.com/magicianlyx/GoTask/trace"
 	"runtime"
 	"time"
 )
 
 type Options struct {
-	AutoMonitorDuration time.Duration // 定时check时长
-	CloseLessThanF      float64       // 定时check活跃线程比例 小于50%时 会关闭当前线程
-	NewGreaterThanF     float64       // 活跃线程比例大于90%时 新任务会创建新线程去跑
-	GoroutineLimit      int           // 线程上限数
-	TaskChannelSize     int           // 任务channel尺寸
+	AutoMonitorDuration time.Duration    // 定时check时长
+	CloseLessThanF      float64          // 定时check活跃线程比例 小于50%时 会关闭当前线程
+	NewGreaterThanF     float64          // 活跃线程比例大于90%时 新任务会创建新线程去跑
+	GoroutineLimit      int              // 线程上限数
+	TaskChannelSize     int              // 任务channel尺寸
+	PanicHandler        PanicHandler     // 任务panic处理方法 为空时输出到日志
+	Clock               clock.Clock      // 时钟 为空时使用系统时钟
+	PriorityLevels      int              // 任务优先级级数 优先级取值为[0, PriorityLevels) 越大越优先
+	PriorityAging       time.Duration    // 优先级老化时长 任务每排队等待该时长相当于提升一个优先级 小于0时严格按优先级执行
+	RejectionPolicy     RejectionPolicy  // 任务通道已满时的拒绝策略
+	RejectionHandler    RejectionHandler // 拒绝策略为RejectCustom时的处理方法
+	Tracer              trace.Tracer     // 埋点 为空时不记录
 }
 
 // 构建默认配置
@@ -21,6 +30,12 @@ func NewDefaultOptions() *Options {
 		NewGreaterThanF:     0.001,
 		GoroutineLimit:      runtime.NumCPU() * 3,
 		TaskChannelSize:     runtime.NumCPU() * 100,
+		PanicHandler:        defaultPanicHandler,
+		Clock:               clock.NewRealClock(),
+		PriorityLevels:      3,
+		PriorityAging:       time.Second,
+		RejectionPolicy:     RejectBlock,
+		Tracer:              trace.NewNoopTracer(),
 	}
 }
 
@@ -42,6 +57,24 @@ func (o *Options) fillDefaultOptions() {
 	if o.TaskChannelSize <= 0 {
 		o.TaskChannelSize = oDefault.TaskChannelSize
 	}
+	if o.PanicHandler == nil {
+		o.PanicHandler = oDefault.PanicHandler
+	}
+	if o.Clock == nil {
+		o.Clock = oDefault.Clock
+	}
+	if o.PriorityLevels <= 0 {
+		o.PriorityLevels = oDefault.PriorityLevels
+	}
+	if o.PriorityAging == time.Duration(0) {
+		o.PriorityAging = oDefault.PriorityAging
+	}
+	if !o.RejectionPolicy.IsValid() || (o.RejectionPolicy == RejectCustom && o.RejectionHandler == nil) {
+		o.RejectionPolicy = oDefault.RejectionPolicy
+	}
+	if o.Tracer == nil {
+		o.Tracer = oDefault.Tracer
+	}
 }
 
 func (o *Options) Clone() *Options {
@@ -51,5 +84,12 @@ func (o *Options) Clone() *Options {
 		o.NewGreaterThanF,
 		o.GoroutineLimit,
 		o.TaskChannelSize,
+		o.PanicHandler,
+		o.Clock,
+		o.PriorityLevels,
+		o.PriorityAging,
+		o.RejectionPolicy,
+		o.RejectionHandler,
+		o.Tracer,
 	}
 }
@@ -0,0 +1,31 @@
+package pool
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// 任务执行时发生panic 被恢复后转换成的错误
+type PanicError struct {
+	Value interface{} // recover得到的值
+	Stack []byte      // 发生panic时的调用栈
+}
+
+func NewPanicError(v interface{}) *PanicError {
+	return &PanicError{
+		Value: v,
+		Stack: debug.Stack(),
+	}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// 线程池任务panic处理方法
+type PanicHandler func(gid GoroutineUID, err *PanicError)
+
+// 默认panic处理方法 输出到日志
+func defaultPanicHandler(gid GoroutineUID, err *PanicError) {
+	printf("goroutine %d recovered from %s", gid, err.Error())
+}
@@ -1,6 +1,8 @@
 package pool
 
 import (
+	"context"
+	"gitee.com/magicianlyx/GoTask/trace"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,13 +11,35 @@ import (
 // 任务函数 gid为执行该任务的线程id
 type TaskObj func(gid GoroutineUID)
 
+// 内部使用的任务函数 ctx为携带执行跨度的context
+type ctxTaskObj func(ctx context.Context, gid GoroutineUID)
+
+func withoutContext(obj TaskObj) ctxTaskObj {
+	return func(ctx context.Context, gid GoroutineUID) {
+		obj(gid)
+	}
+}
+
 type GoroutinePool struct {
-	c chan TaskObj
-	e chan struct{} // 停止所有线程信号
-	l sync.RWMutex
-	m *DynamicPoolMonitor
-	o *Options
-	s int64 // 0未关闭 1已关闭
+	c  chan struct{} // 任务令牌 每个令牌对应优先级队列中的一个任务 长度即为排队中的任务数
+	q  *priorityQueue
+	p  chan struct{} // 任务位 入队前占用 任务出队后释放 容量即为TaskChannelSize
+	r  int64         // 被拒绝的任务数
+	e  chan struct{} // 停止所有线程信号
+	d  chan struct{} // 排空任务后停止所有线程信号
+	l  sync.RWMutex  // 保证关闭后不会再有任务进入任务通道
+	m  *DynamicPoolMonitor
+	o  *Options
+	s  int64 // 0未关闭 1已关闭
+	wg sync.WaitGroup
+	eo sync.Once
+	do sync.Once
+}
+
+// 线程池关闭报告
+type ShutdownReport struct {
+	Abandoned int // 截止时仍在执行 未等待其结束的任务数
+	Discarded int // 截止时或线程全部退出后仍在排队 未被执行而丢弃的任务数
 }
 
 func NewGoroutinePool(options *Options) *GoroutinePool {
@@ -23,16 +47,21 @@ func NewGoroutinePool(options *Options) *GoroutinePool {
 	options.fillDefaultOptions()
 	m := NewDynamicPoolMonitor(options)
 	return &GoroutinePool{
-		c: make(chan TaskObj, options.TaskChannelSize),
+		c: make(chan struct{}, options.TaskChannelSize),
+		p: make(chan struct{}, options.TaskChannelSize),
+		q: newPriorityQueue(options.PriorityLevels, options.PriorityAging),
 		e: make(chan struct{}),
+		d: make(chan struct{}),
 		m: m,
 		o: options,
 		s: 0,
 	}
 }
 
-// 设置关闭组件标识
+// 设置关闭组件标识 返回之后不会再有任务进入任务通道
 func (g *GoroutinePool) close() {
+	g.l.Lock()
+	defer g.l.Unlock()
 	atomic.StoreInt64(&g.s, 1)
 }
 
@@ -41,21 +70,151 @@ func (g *GoroutinePool) isClose() bool {
 	return atomic.LoadInt64(&g.s) == 1
 }
 
-// 向线程池推一个任务
+// 向线程池推一个最低优先级的任务
+// 任务通道已满时按拒绝策略处理 默认阻塞等待 组件关闭后推送的任务会被丢弃
 func (g *GoroutinePool) Put(obj TaskObj) {
-	if !g.isClose() {
-		g.c <- obj
-		g.checkPressure()
+	g.PutWithPriority(obj, 0)
+}
+
+// 向线程池推一个指定优先级的任务 优先级越大越先执行 超出范围时取最近的有效值
+// 任务通道已满时按拒绝策略处理 默认阻塞等待 组件关闭后推送的任务会被丢弃
+func (g *GoroutinePool) PutWithPriority(obj TaskObj, priority int) {
+	g.put(context.Background(), withoutContext(obj), nil, priority, true)
+}
+
+// 推送任务 discard在任务未执行而被丢弃时调用
+// wait为true且拒绝策略为RejectBlock时 等待任务位直到ctx结束 ctx同时作为执行跨度的上游
+func (g *GoroutinePool) put(ctx context.Context, obj ctxTaskObj, discard func(error), priority int, wait bool) error {
+	err := g.enqueue(ctx, obj, discard, priority, wait)
+	if err == errNoSpace {
+		err = g.reject(ctx, obj, discard, priority)
+	} else if err != nil {
+		atomic.AddInt64(&g.r, 1)
+		if discard != nil {
+			discard(err)
+		}
 	}
+	return err
 }
 
-// 关闭组件
+// 占用任务位并入队 组件已关闭时返回ErrPoolIsClosed 没有任务位时返回errNoSpace
+// 等待任务位时不持有锁 防止阻塞关闭组件
+func (g *GoroutinePool) enqueue(ctx context.Context, obj ctxTaskObj, discard func(error), priority int, wait bool) error {
+	if g.isClose() {
+		return ErrPoolIsClosed
+	}
+	if wait && g.o.RejectionPolicy == RejectBlock {
+		select {
+		case g.p <- struct{}{}:
+		case <-ctx.Done():
+			return errNoSpace
+		case <-g.e:
+			return ErrPoolIsClosed
+		case <-g.d:
+			return ErrPoolIsClosed
+		}
+	} else {
+		select {
+		case g.p <- struct{}{}:
+		default:
+			return errNoSpace
+		}
+	}
+
+	g.l.RLock()
+	if g.isClose() {
+		// 等待期间组件被关闭 释放任务位
+		g.l.RUnlock()
+		<-g.p
+		return ErrPoolIsClosed
+	}
+	// 先入队再发送令牌 保证取得令牌的线程总能取到任务 令牌数不超过任务位数 发送不会阻塞
+	now := g.o.Clock.Now()
+	g.q.push(ctx, obj, discard, priority, now)
+	g.o.Tracer.Enqueue(&trace.QueueEvent{
+		Component:   trace.ComponentPool,
+		Priority:    g.q.clamp(priority),
+		EnqueueTime: now,
+	})
+	g.c <- struct{}{}
+	g.l.RUnlock()
+	g.checkPressure()
+	return nil
+}
+
+// 取出一个任务并释放任务位 调用方需已取得令牌
+func (g *GoroutinePool) take() *taskItem {
+	item, ok := g.q.pop()
+	<-g.p
+	if ok {
+		g.o.Tracer.Dequeue(&trace.QueueEvent{
+			Component:   trace.ComponentPool,
+			Priority:    item.priority,
+			EnqueueTime: item.enqueued,
+			DequeueTime: g.o.Clock.Now(),
+		})
+		return item
+	}
+	return nil
+}
+
+// 丢弃任务通道中剩余的任务 返回丢弃的任务数
+func (g *GoroutinePool) discardAll() int {
+	n := 0
+	for {
+		select {
+		case <-g.c:
+			item, ok := g.q.pop()
+			<-g.p
+			if ok {
+				n++
+				if item.discard != nil {
+					item.discard(ErrPoolIsClosed)
+				}
+			}
+		default:
+			return n
+		}
+	}
+}
+
+// 关闭组件 丢弃排队中的任务 不等待执行中的任务结束
 func (g *GoroutinePool) Stop() {
-	if !g.isClose() {
-		g.close()
-		close(g.c)
+	g.close()
+	g.eo.Do(func() {
 		close(g.e)
+	})
+	g.discardAll()
+}
+
+// 优雅关闭组件 不再接受新任务 等待排队中及执行中的任务结束
+// ctx到期时停止等待 并返回未完成的任务统计 可重复调用
+// 线程全部退出后仍在排队的任务将被丢弃 保证其Future得到结果
+func (g *GoroutinePool) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	g.close()
+	g.do.Do(func() {
+		close(g.d)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		// 排空时没有存活线程的任务不会被执行
+		return &ShutdownReport{Discarded: g.discardAll()}, nil
+	case <-ctx.Done():
 	}
+
+	// 到期 停止所有线程 丢弃排队中的任务
+	report := &ShutdownReport{Abandoned: g.m.GetCurrentActiveCount()}
+	g.eo.Do(func() {
+		close(g.e)
+	})
+	report.Discarded = g.discardAll()
+	return report, ctx.Err()
 }
 
 // 根据压力尝试创建线程
@@ -69,28 +228,27 @@ func (g *GoroutinePool) checkPressure() {
 // 新建一个线程
 func (g *GoroutinePool) createGoroutine(gid GoroutineUID) chan<- struct{} {
 	c := make(chan struct{})
+	g.wg.Add(1)
 	go func(gid GoroutineUID) {
-		t := time.NewTicker(g.o.AutoMonitorDuration)
+		defer g.wg.Done()
+		t := g.o.Clock.NewTicker(g.o.AutoMonitorDuration)
 		for {
 			select {
-			case task := <-g.c:
-				if task != nil {
-					// 执行任务task
+			case <-g.c:
+				if item := g.take(); item != nil {
+					// 执行任务
 					g.m.SwitchGoRoutineStatus(gid)
-					task(gid)
+					g.runTask(item.ctx, gid, item.obj)
 					g.m.SwitchGoRoutineStatus(gid)
-				} else if g.isClose() {
-					// 主线程主动关闭
-					break
 				}
-			case <-t.C:
+			case <-t.C():
 				// 根据压力尝试关闭线程
 				t.Stop()
 				if g.m.TryDestroy(gid) {
 					t.Stop()
 					return
 				} else {
-					t = time.NewTicker(g.o.AutoMonitorDuration)
+					t = g.o.Clock.NewTicker(g.o.AutoMonitorDuration)
 				}
 			case <-c:
 				// 单线程主动关闭
@@ -102,12 +260,59 @@ func (g *GoroutinePool) createGoroutine(gid GoroutineUID) chan<- struct{} {
 				g.m.Destroy(gid)
 				t.Stop()
 				return
+			case <-g.d:
+				// 主线程优雅关闭 执行完排队中的任务后退出
+				g.drain(gid)
+				g.m.Destroy(gid)
+				t.Stop()
+				return
 			}
 		}
 	}(gid)
 	return c
 }
 
+// 执行任务通道中剩余的任务 直到通道为空或组件被强制关闭
+func (g *GoroutinePool) drain(gid GoroutineUID) {
+	for {
+		select {
+		case <-g.e:
+			return
+		default:
+		}
+		select {
+		case <-g.c:
+			if item := g.take(); item != nil {
+				g.m.SwitchGoRoutineStatus(gid)
+				g.runTask(item.ctx, gid, item.obj)
+				g.m.SwitchGoRoutineStatus(gid)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// 执行任务 任务panic时恢复并交给panic处理方法 保证线程继续存活
+// 执行跨度以ctx为上游 携带执行跨度的context传递给任务
+func (g *GoroutinePool) runTask(ctx context.Context, gid GoroutineUID, task ctxTaskObj) {
+	ctx, span := g.o.Tracer.StartRun(ctx, &trace.RunInfo{
+		Component: trace.ComponentPool,
+		Gid:       int64(gid),
+		StartTime: g.o.Clock.Now(),
+	})
+	defer func() {
+		if r := recover(); r != nil {
+			err := NewPanicError(r)
+			span.End(err)
+			g.o.PanicHandler(gid, err)
+			return
+		}
+		span.End(nil)
+	}()
+	task(ctx, gid)
+}
+
 // 获取状态总结
 func (g *GoroutinePool) GetStatusSettle() map[GoroutineStatus]time.Duration {
 	return g.m.GetStatusSettle()
@@ -135,3 +340,8 @@ func (g *GoroutinePool) GetWorkCount() int {
 	}
 	return len(g.c)
 }
+
+// 获取各优先级等待线程执行的任务数 下标为优先级
+func (g *GoroutinePool) GetWorkCountByPriority() []int {
+	return g.q.getDepth()
+}
@@ -1,9 +1,11 @@
 package pool
 
 import (
+	"context"
 	"fmt"
 	"gitee.com/magicianlyx/GoTask/utils"
 	"math/rand"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -76,3 +78,239 @@ func TestNewGoroutinePool(t *testing.T) {
 	
 	time.Sleep(time.Hour)
 }
+
+func TestGoroutinePool_Panic(t *testing.T) {
+	panics := make(chan *PanicError, 1)
+	pool := NewGoroutinePool(&Options{
+		GoroutineLimit: 1,
+		PanicHandler: func(gid GoroutineUID, err *PanicError) {
+			panics <- err
+		},
+	})
+	defer pool.Stop()
+
+	pool.Put(func(gid GoroutineUID) {
+		panic("boom")
+	})
+	select {
+	case err := <-panics:
+		if err.Value != "boom" || len(err.Stack) == 0 {
+			t.Errorf("unexpected panic error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic not handled")
+	}
+
+	done := make(chan struct{})
+	pool.Put(func(gid GoroutineUID) {
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not survive panic")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if active := pool.GetCurrentActiveCount(); active != 0 {
+		t.Errorf("active count should be 0, got %d", active)
+	}
+	if count := pool.GetGoroutineCount(); count != 1 {
+		t.Errorf("goroutine count should be 1, got %d", count)
+	}
+}
+
+func TestGoroutinePool_Shutdown(t *testing.T) {
+	pool := NewGoroutinePool(&Options{GoroutineLimit: 1})
+	var ran int32
+	for i := 0; i < 5; i++ {
+		pool.Put(func(gid GoroutineUID) {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	report, err := pool.Shutdown(ctx)
+	if err != nil || report.Abandoned != 0 || report.Discarded != 0 {
+		t.Errorf("unexpected shutdown result: %+v %v", report, err)
+	}
+	if n := atomic.LoadInt32(&ran); n != 5 {
+		t.Errorf("queued tasks should be drained, ran %d", n)
+	}
+	pool.Put(func(gid GoroutineUID) {
+		t.Error("task put after shutdown should be dropped")
+	})
+	if _, err = pool.Shutdown(ctx); err != nil {
+		t.Errorf("second shutdown: %v", err)
+	}
+}
+
+// 池已满时阻塞的Put不应阻塞Stop
+func TestGoroutinePool_StopWithBlockedPut(t *testing.T) {
+	pool := NewGoroutinePool(&Options{GoroutineLimit: 1, TaskChannelSize: 1})
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	pool.Put(func(gid GoroutineUID) {
+		close(started)
+		<-block
+	})
+	<-started
+	pool.Put(func(gid GoroutineUID) {})
+	returned := make(chan struct{})
+	go func() {
+		pool.Put(func(gid GoroutineUID) {})
+		close(returned)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+	for _, c := range []chan struct{}{stopped, returned} {
+		select {
+		case <-c:
+		case <-time.After(time.Second):
+			t.Fatal("Stop or blocked Put hangs")
+		}
+	}
+	if err := pool.TryPut(func(gid GoroutineUID) {}); err != ErrPoolIsClosed {
+		t.Errorf("TryPut after stop: %v", err)
+	}
+}
+
+func TestGoroutinePool_ShutdownDeadline(t *testing.T) {
+	pool := NewGoroutinePool(&Options{GoroutineLimit: 1})
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	pool.Put(func(gid GoroutineUID) {
+		close(started)
+		<-block
+	})
+	<-started
+	for i := 0; i < 3; i++ {
+		pool.Put(func(gid GoroutineUID) {})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := pool.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if report.Abandoned != 1 || report.Discarded != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+// 关闭时仍在排队的Future 无论是否到期都应得到结果
+func TestGoroutinePool_ShutdownResolvesFutures(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// 到期时丢弃排队中的任务
+	pool := NewGoroutinePool(&Options{GoroutineLimit: 1})
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	pool.Put(func(gid GoroutineUID) {
+		close(started)
+		<-block
+	})
+	<-started
+	queued := []*Future{
+		pool.Submit(func(ctx context.Context) (interface{}, error) { return 1, nil }),
+		pool.Submit(func(ctx context.Context) (interface{}, error) { return 2, nil }),
+	}
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if report, err := pool.Shutdown(short); err != context.DeadlineExceeded || report.Discarded != 2 {
+		t.Errorf("unexpected shutdown result: %+v %v", report, err)
+	}
+	for i, f := range queued {
+		if _, err := f.Wait(ctx); err != ErrPoolIsClosed {
+			t.Errorf("future %d: %v", i, err)
+		}
+	}
+
+	// 排空结束后仍留在队列中的任务 如入队时已没有存活线程
+	pool = NewGoroutinePool(&Options{GoroutineLimit: 1})
+	f := newFuture(context.Background())
+	pool.p <- struct{}{}
+	pool.q.push(f.ctx, func(ctx context.Context, gid GoroutineUID) {
+		f.complete(nil, nil)
+	}, func(err error) {
+		f.complete(nil, err)
+	}, 0, time.Now())
+	pool.c <- struct{}{}
+	if report, err := pool.Shutdown(ctx); err != nil || report.Discarded != 1 {
+		t.Errorf("unexpected shutdown result: %+v %v", report, err)
+	}
+	if _, err := f.Wait(ctx); err != ErrPoolIsClosed {
+		t.Errorf("stranded future: %v", err)
+	}
+}
+
+func TestGoroutinePool_PutWithPriority(t *testing.T) {
+	pool := NewGoroutinePool(&Options{GoroutineLimit: 1, PriorityLevels: 2, PriorityAging: -1})
+	defer pool.Stop()
+	block := make(chan struct{})
+	started := make(chan struct{})
+	pool.Put(func(gid GoroutineUID) {
+		close(started)
+		<-block
+	})
+	<-started
+	order := make(chan string, 4)
+	for _, name := range []string{"cleanup1", "cleanup2"} {
+		name := name
+		pool.Put(func(gid GoroutineUID) {
+			order <- name
+		})
+	}
+	pool.PutWithPriority(func(gid GoroutineUID) {
+		order <- "request"
+	}, 5)
+	if depth := pool.GetWorkCountByPriority(); depth[0] != 2 || depth[1] != 1 || pool.GetWorkCount() != 3 {
+		t.Errorf("unexpected depth: %v %d", depth, pool.GetWorkCount())
+	}
+	close(block)
+
+	for _, want := range []string{"request", "cleanup1", "cleanup2"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Errorf("want %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not executed", want)
+		}
+	}
+}
+
+func TestPriorityQueue_Aging(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	q := newPriorityQueue(3, time.Second)
+	var got []int
+	push := func(id int, priority int, at time.Time) {
+		q.push(context.Background(), func(ctx context.Context, gid GoroutineUID) {
+			got = append(got, id)
+		}, nil, priority, at)
+	}
+	push(1, 0, now)
+	push(2, 2, now.Add(time.Second))
+	// 低优先级任务已等待超过2个老化时长 优先于刚入队的高优先级任务
+	push(3, 2, now.Add(2*time.Second+time.Millisecond))
+	for {
+		item, ok := q.pop()
+		if !ok {
+			break
+		}
+		item.obj(item.ctx, 0)
+	}
+	if len(got) != 3 || got[0] != 2 || got[1] != 1 || got[2] != 3 {
+		t.Errorf("unexpected order: %v", got)
+	}
+}
@@ -0,0 +1,144 @@
+package pool
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// 等待执行的任务项
+type taskItem struct {
+	ctx      context.Context // 推送时的context 作为执行跨度的上游
+	obj      ctxTaskObj
+	discard  func(err error) // 任务未执行而被丢弃时调用 可为空
+	priority int
+	deadline time.Time // 入队时刻减去优先级对应的老化时长 越早越先执行
+	seq      uint64    // 入队序号 排序相同时先入队先执行
+	enqueued time.Time // 入队时刻
+}
+
+// 任务最小堆 非线程安全
+type taskHeap struct {
+	items  []*taskItem
+	strict bool // 严格按优先级排序 不老化
+}
+
+func (h *taskHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *taskHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.strict {
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+	} else if !a.deadline.Equal(b.deadline) {
+		return a.deadline.Before(b.deadline)
+	}
+	return a.seq < b.seq
+}
+
+func (h *taskHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*taskItem))
+}
+
+func (h *taskHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return item
+}
+
+// 任务优先级队列（线程安全） 优先级高的任务先出队
+// 任务每等待一个老化时长相当于提升一个优先级 防止低优先级任务饿死
+type priorityQueue struct {
+	l      sync.Mutex
+	h      *taskHeap
+	depth  []int // 各优先级排队中的任务数
+	levels int
+	aging  time.Duration
+	seq    uint64
+}
+
+func newPriorityQueue(levels int, aging time.Duration) *priorityQueue {
+	return &priorityQueue{
+		l:      sync.Mutex{},
+		h:      &taskHeap{items: make([]*taskItem, 0), strict: aging < 0},
+		depth:  make([]int, levels),
+		levels: levels,
+		aging:  aging,
+	}
+}
+
+// 将优先级限制在[0, levels)内
+func (q *priorityQueue) clamp(priority int) int {
+	if priority < 0 {
+		return 0
+	}
+	if priority >= q.levels {
+		return q.levels - 1
+	}
+	return priority
+}
+
+func (q *priorityQueue) push(ctx context.Context, obj ctxTaskObj, discard func(error), priority int, now time.Time) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	priority = q.clamp(priority)
+	q.seq++
+	heap.Push(q.h, &taskItem{
+		ctx:      ctx,
+		obj:      obj,
+		discard:  discard,
+		priority: priority,
+		deadline: now.Add(-time.Duration(priority) * q.aging),
+		seq:      q.seq,
+		enqueued: now,
+	})
+	q.depth[priority]++
+}
+
+func (q *priorityQueue) pop() (*taskItem, bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	if q.h.Len() == 0 {
+		return nil, false
+	}
+	item := heap.Pop(q.h).(*taskItem)
+	q.depth[item.priority]--
+	return item, true
+}
+
+// 取出最早入队的任务
+func (q *priorityQueue) popOldest() (*taskItem, bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	if q.h.Len() == 0 {
+		return nil, false
+	}
+	oldest := 0
+	for i, item := range q.h.items {
+		if item.seq < q.h.items[oldest].seq {
+			oldest = i
+		}
+	}
+	item := heap.Remove(q.h, oldest).(*taskItem)
+	q.depth[item.priority]--
+	return item, true
+}
+
+// 各优先级排队中的任务数 下标为优先级
+func (q *priorityQueue) getDepth() []int {
+	q.l.Lock()
+	defer q.l.Unlock()
+	depth := make([]int, len(q.depth))
+	copy(depth, q.depth)
+	return depth
+}
@@ -0,0 +1,145 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrPoolIsClosed  = errors.New("goroutine pool is closed")
+	ErrPoolIsFull    = errors.New("goroutine pool task channel is full")
+	ErrTaskDiscarded = errors.New("task is discarded by rejection policy")
+
+	errNoSpace = errors.New("no space in task channel")
+)
+
+// 任务通道已满时的拒绝策略
+type RejectionPolicy int
+
+const (
+	RejectBlock         RejectionPolicy = 0 // Put阻塞等待任务位 TryPut返回ErrPoolIsFull PutWithContext超时返回ctx的错误
+	RejectAbort         RejectionPolicy = 1 // 拒绝任务并返回ErrPoolIsFull
+	RejectCallerRuns    RejectionPolicy = 2 // 在调用方线程中直接执行任务 gid为-1
+	RejectDiscardOldest RejectionPolicy = 3 // 丢弃最早入队的任务 再尝试入队
+	RejectDiscardNewest RejectionPolicy = 4 // 丢弃当前任务 不返回错误
+	RejectCustom        RejectionPolicy = 5 // 交给Options.RejectionHandler处理
+)
+
+func (p RejectionPolicy) ToString() string {
+	switch p {
+	case RejectAbort:
+		return "abort"
+	case RejectCallerRuns:
+		return "caller_runs"
+	case RejectDiscardOldest:
+		return "discard_oldest"
+	case RejectDiscardNewest:
+		return "discard_newest"
+	case RejectCustom:
+		return "custom"
+	default:
+		return "block"
+	}
+}
+
+func (p RejectionPolicy) IsValid() bool {
+	return p >= RejectBlock && p <= RejectCustom
+}
+
+// 自定义拒绝处理方法 返回的错误将作为推送任务的错误
+type RejectionHandler func(obj TaskObj, g *GoroutinePool) error
+
+// 尝试推送一个最低优先级的任务 不阻塞 任务通道已满时按拒绝策略处理
+// 组件已关闭时返回ErrPoolIsClosed
+func (g *GoroutinePool) TryPut(obj TaskObj) error {
+	return g.TryPutWithPriority(obj, 0)
+}
+
+// 尝试推送一个指定优先级的任务 不阻塞
+func (g *GoroutinePool) TryPutWithPriority(obj TaskObj, priority int) error {
+	return g.put(context.Background(), withoutContext(obj), nil, priority, false)
+}
+
+// 推送一个最低优先级的任务 拒绝策略为RejectBlock时最多等待到ctx结束
+// 其余拒绝策略下不等待 任务通道已满时直接按拒绝策略处理 等待期间组件关闭时返回ErrPoolIsClosed
+func (g *GoroutinePool) PutWithContext(ctx context.Context, obj TaskObj) error {
+	return g.PutWithContextPriority(ctx, obj, 0)
+}
+
+// 推送一个指定优先级的任务 拒绝策略为RejectBlock时最多等待到ctx结束
+func (g *GoroutinePool) PutWithContextPriority(ctx context.Context, obj TaskObj, priority int) error {
+	return g.put(ctx, withoutContext(obj), nil, priority, true)
+}
+
+// 推送一个最低优先级的任务 拒绝策略为RejectBlock时最多等待d时长
+func (g *GoroutinePool) PutTimeout(obj TaskObj, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return g.PutWithContext(ctx, obj)
+}
+
+// 获取被拒绝的任务数 包含组件关闭后推送的任务
+func (g *GoroutinePool) GetRejectedCount() int64 {
+	return atomic.LoadInt64(&g.r)
+}
+
+// 按拒绝策略处理没有任务位的任务
+func (g *GoroutinePool) reject(ctx context.Context, obj ctxTaskObj, discard func(error), priority int) error {
+	atomic.AddInt64(&g.r, 1)
+	if discard == nil {
+		discard = func(error) {}
+	}
+	switch g.o.RejectionPolicy {
+	case RejectCallerRuns:
+		g.runTask(ctx, -1, obj)
+		return nil
+	case RejectDiscardOldest:
+		if g.discardOldest() {
+			err := g.enqueue(ctx, obj, discard, priority, false)
+			if err != errNoSpace {
+				if err != nil {
+					discard(err)
+				}
+				return err
+			}
+		}
+		// 仍然没有任务位 丢弃当前任务
+		discard(ErrTaskDiscarded)
+		return nil
+	case RejectDiscardNewest:
+		discard(ErrTaskDiscarded)
+		return nil
+	case RejectCustom:
+		err := g.o.RejectionHandler(func(gid GoroutineUID) {
+			obj(ctx, gid)
+		}, g)
+		if err != nil {
+			discard(err)
+		}
+		return err
+	default:
+		err := ErrPoolIsFull
+		if g.o.RejectionPolicy == RejectBlock && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		discard(err)
+		return err
+	}
+}
+
+// 丢弃最早入队的任务 返回是否丢弃成功
+func (g *GoroutinePool) discardOldest() bool {
+	select {
+	case <-g.c:
+		item, ok := g.q.popOldest()
+		<-g.p
+		if ok && item.discard != nil {
+			item.discard(ErrTaskDiscarded)
+		}
+		return ok
+	default:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 创建唯一线程被阻塞且任务通道已满的线程池
+func newSaturatedPool(t *testing.T, o *Options, queued TaskObj) (*GoroutinePool, chan struct{}) {
+	o.GoroutineLimit = 1
+	o.TaskChannelSize = 1
+	pool := NewGoroutinePool(o)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	pool.Put(func(gid GoroutineUID) {
+		close(started)
+		<-block
+	})
+	<-started
+	if err := pool.TryPut(queued); err != nil {
+		t.Fatalf("queue task: %v", err)
+	}
+	return pool, block
+}
+
+func TestGoroutinePool_Rejection(t *testing.T) {
+	nop := func(gid GoroutineUID) {}
+
+	pool, block := newSaturatedPool(t, &Options{}, nop)
+	if err := pool.TryPut(nop); err != ErrPoolIsFull {
+		t.Errorf("block: try put %v", err)
+	}
+	if err := pool.PutTimeout(nop, 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("block: put timeout %v", err)
+	}
+	close(block)
+	pool.Stop()
+	if err := pool.TryPut(nop); err != ErrPoolIsClosed {
+		t.Errorf("put after stop: %v", err)
+	}
+	if n := pool.GetRejectedCount(); n != 3 {
+		t.Errorf("rejected count %d", n)
+	}
+
+	pool, block = newSaturatedPool(t, &Options{RejectionPolicy: RejectAbort}, nop)
+	if err := pool.PutTimeout(nop, time.Second); err != ErrPoolIsFull {
+		t.Errorf("abort: %v", err)
+	}
+	close(block)
+	pool.Stop()
+
+	pool, block = newSaturatedPool(t, &Options{RejectionPolicy: RejectCallerRuns}, nop)
+	var caller GoroutineUID
+	if err := pool.TryPut(func(gid GoroutineUID) { caller = gid }); err != nil || caller != -1 {
+		t.Errorf("caller runs: %v %d", err, caller)
+	}
+	close(block)
+	pool.Stop()
+
+	ran := make(chan string, 2)
+	pool, block = newSaturatedPool(t, &Options{RejectionPolicy: RejectDiscardOldest}, func(gid GoroutineUID) {
+		ran <- "oldest"
+	})
+	if err := pool.TryPut(func(gid GoroutineUID) { ran <- "newest" }); err != nil {
+		t.Errorf("discard oldest: %v", err)
+	}
+	close(block)
+	if got := <-ran; got != "newest" {
+		t.Errorf("discard oldest: ran %s", got)
+	}
+	pool.Stop()
+
+	pool, block = newSaturatedPool(t, &Options{RejectionPolicy: RejectDiscardNewest}, nop)
+	f := pool.Submit(func(ctx context.Context) (interface{}, error) { return nil, nil })
+	if _, err := f.Result(); err != ErrTaskDiscarded {
+		t.Errorf("discard newest: %v", err)
+	}
+	close(block)
+	pool.Stop()
+
+	custom := errors.New("custom")
+	pool, block = newSaturatedPool(t, &Options{
+		RejectionPolicy: RejectCustom,
+		RejectionHandler: func(obj TaskObj, g *GoroutinePool) error {
+			return custom
+		},
+	}, nop)
+	if err := pool.TryPut(nop); err != custom || pool.GetRejectedCount() != 1 {
+		t.Errorf("custom: %v", err)
+	}
+	close(block)
+	pool.Stop()
+}
+
+// 池已满时阻塞等待任务位的推送 在组件关闭时返回ErrPoolIsClosed 且不阻塞关闭
+func TestGoroutinePool_BlockedPutRacesShutdown(t *testing.T) {
+	nop := func(gid GoroutineUID) {}
+	for _, name := range []string{"stop", "shutdown"} {
+		pool, block := newSaturatedPool(t, &Options{}, nop)
+		putErr := make(chan error, 1)
+		go func() {
+			putErr <- pool.PutWithContext(context.Background(), nop)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		closed := make(chan struct{})
+		go func() {
+			if name == "stop" {
+				pool.Stop()
+			} else {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+				pool.Shutdown(ctx)
+			}
+			close(closed)
+		}()
+		select {
+		case err := <-putErr:
+			if err != ErrPoolIsClosed {
+				t.Errorf("%s: blocked put got %v", name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: blocked put hangs", name)
+		}
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: pool close hangs", name)
+		}
+		if err := pool.TryPut(nop); err != ErrPoolIsClosed {
+			t.Errorf("%s: try put after close: %v", name, err)
+		}
+		close(block)
+	}
+}
@@ -1,6 +1,7 @@
 package pool
 
 import (
+	"gitee.com/magicianlyx/GoTask/clock"
 	"sync"
 	"time"
 )
@@ -51,6 +52,10 @@ type StatusSwitch struct {
 }
 
 func NewStatusSwitch(preStatus, status GoroutineStatus) *StatusSwitch {
+	return newStatusSwitch(preStatus, status, time.Now())
+}
+
+func newStatusSwitch(preStatus, status GoroutineStatus, now time.Time) *StatusSwitch {
 	if preStatus == status {
 		// 非法逻辑
 		printf(
@@ -60,7 +65,7 @@ func NewStatusSwitch(preStatus, status GoroutineStatus) *StatusSwitch {
 		)
 	}
 	return &StatusSwitch{
-		Time:      time.Now(),
+		Time:      now,
 		PreStatus: preStatus,
 		Status:    status,
 	}
@@ -91,13 +96,19 @@ type RecentRecord struct {
 	l sync.RWMutex    // 锁
 	d time.Duration   // 最近有效时长
 	m []*StatusSwitch // 存储切换记录
+	c clock.Clock     // 时钟
 }
 
 func NewRecentRecord(d time.Duration) *RecentRecord {
+	return NewRecentRecordWithClock(d, clock.NewRealClock())
+}
+
+func NewRecentRecordWithClock(d time.Duration, c clock.Clock) *RecentRecord {
 	return &RecentRecord{
 		l: sync.RWMutex{},
 		d: d,
 		m: make([]*StatusSwitch, 0),
+		c: c,
 	}
 }
 
@@ -107,7 +118,7 @@ func (l *RecentRecord) GetRecentSettle() map[GoroutineStatus]time.Duration {
 	lc := l.Clone() // 创建一个副本再去统计
 	m := lc.m
 	mLen := len(m)
-	e := time.Now()
+	e := l.c.Now()
 
 	o := make(map[GoroutineStatus]time.Duration)
 	for i := mLen - 1; i >= 0; i-- {
@@ -123,7 +134,7 @@ func (l *RecentRecord) GetRecentSettle() map[GoroutineStatus]time.Duration {
 // 调整 去除过期的切换记录
 func (l *RecentRecord) adjustRecord() {
 	// 计算有效时间最早时刻
-	now := time.Now()
+	now := l.c.Now()
 	limit := now.Add(-l.d)
 
 	l.l.Lock()
@@ -163,7 +174,7 @@ func (l *RecentRecord) adjustRecord() {
 func (l *RecentRecord) AddSwitchRecord(preStatus, status GoroutineStatus) {
 	l.l.Lock()
 	defer l.l.Unlock()
-	l.m = append(l.m, NewStatusSwitch(preStatus, status))
+	l.m = append(l.m, newStatusSwitch(preStatus, status, l.c.Now()))
 }
 
 // 生成副本
@@ -179,5 +190,6 @@ func (l *RecentRecord) Clone() *RecentRecord {
 		l: sync.RWMutex{},
 		d: l.d,
 		m: r,
+		c: l.c,
 	}
 }
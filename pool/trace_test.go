@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"context"
+	"gitee.com/magicianlyx/GoTask/trace"
+	"testing"
+	"time"
+)
+
+func TestGoroutinePool_Tracer(t *testing.T) {
+	r := trace.NewRecorder(nil)
+	pool := NewGoroutinePool(&Options{Tracer: r, PanicHandler: func(gid GoroutineUID, err *PanicError) {}})
+	defer pool.Stop()
+	done := make(chan struct{})
+	pool.PutWithPriority(func(gid GoroutineUID) {
+		defer close(done)
+		panic("boom")
+	}, 1)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task not executed")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		spans := r.Spans()
+		if len(spans) == 1 && spans[0].Ended {
+			if _, ok := spans[0].Error.(*PanicError); !ok || spans[0].Info.Component != trace.ComponentPool {
+				t.Errorf("unexpected span: %+v", spans[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("span not ended: %+v", spans)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	enq, deq := r.Enqueued(), r.Dequeued()
+	if len(enq) != 1 || len(deq) != 1 || deq[0].Priority != 1 || deq[0].Wait() < 0 || deq[0].DequeueTime.IsZero() {
+		t.Errorf("unexpected queue events: %+v %+v", enq, deq)
+	}
+}
+
+func TestGoroutinePool_SubmitWithContextSpan(t *testing.T) {
+	r := trace.NewRecorder(nil)
+	pool := NewGoroutinePool(&Options{Tracer: r})
+	defer pool.Stop()
+	parent, parentSpan := r.StartRun(context.Background(), &trace.RunInfo{Component: trace.ComponentScheduler})
+	f := pool.SubmitWithContext(parent, func(ctx context.Context) (interface{}, error) {
+		_, ok := trace.SpanFromContext(ctx)
+		return ok, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := f.Wait(ctx)
+	if err != nil || v != true {
+		t.Fatalf("span not passed to task: %v %v", v, err)
+	}
+	parentSpan.End(nil)
+	spans := r.Spans()
+	if len(spans) != 2 || spans[1].Info.Component != trace.ComponentPool || spans[1].ParentID != spans[0].ID {
+		t.Errorf("unexpected spans: %+v", spans)
+	}
+
+	// 提交时的ctx结束后任务被取消
+	cctx, ccancel := context.WithCancel(context.Background())
+	ccancel()
+	f = pool.SubmitWithContext(cctx, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	if _, err := f.Wait(ctx); err != ErrFutureCancelled {
+		t.Errorf("expected ErrFutureCancelled, got %v", err)
+	}
+}
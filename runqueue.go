@@ -0,0 +1,150 @@
+package GoTask
+
+import (
+	"container/heap"
+	"gitee.com/magicianlyx/GoTask/task"
+	"sync"
+	"time"
+)
+
+// 默认优先级老化时长
+const DefaultPriorityAging = 5 * time.Second
+
+// 等待执行的任务项
+type runItem struct {
+	run      *taskRun
+	priority task.Priority
+	deadline time.Time // 入队时刻减去优先级对应的老化时长 越早越先执行
+	seq      uint64    // 入队序号 deadline相同时先入队先执行
+}
+
+// 按deadline排序的最小堆 非线程安全
+type runHeap []*runItem
+
+func (h runHeap) Len() int {
+	return len(h)
+}
+
+func (h runHeap) Less(i, j int) bool {
+	if h[i].deadline.Equal(h[j].deadline) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].deadline.Before(h[j].deadline)
+}
+
+func (h runHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	*h = append(*h, x.(*runItem))
+}
+
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// 等待执行的任务队列（线程安全） 优先级高的任务先出队
+// 任务每等待一个老化时长相当于提升一个优先级 防止低优先级任务饿死
+// 由于所有任务以相同速度老化 排序只取决于入队时刻减去优先级对应的老化时长 不随时间改变
+type runQueue struct {
+	l      sync.Mutex
+	h      runHeap
+	depth  map[task.Priority]int // 各优先级排队中的任务数
+	seq    uint64
+	aging  time.Duration
+	signal chan struct{} // 有任务入队的通知
+	closed bool
+}
+
+func newRunQueue(aging time.Duration) *runQueue {
+	return &runQueue{
+		l:      sync.Mutex{},
+		h:      make(runHeap, 0),
+		depth:  make(map[task.Priority]int),
+		aging:  aging,
+		signal: make(chan struct{}, 1),
+	}
+}
+
+// 入队 队列已关闭时返回false
+func (q *runQueue) push(run *taskRun, now time.Time) bool {
+	q.l.Lock()
+	if q.closed {
+		q.l.Unlock()
+		return false
+	}
+	p := run.ti.GetOptions().Priority
+	q.seq++
+	heap.Push(&q.h, &runItem{
+		run:      run,
+		priority: p,
+		deadline: now.Add(-time.Duration(p) * q.aging),
+		seq:      q.seq,
+	})
+	q.depth[p]++
+	q.l.Unlock()
+	q.notify()
+	return true
+}
+
+// 出队 队列为空时返回false
+func (q *runQueue) pop() (*taskRun, bool) {
+	q.l.Lock()
+	if len(q.h) == 0 {
+		q.l.Unlock()
+		return nil, false
+	}
+	item := heap.Pop(&q.h).(*runItem)
+	q.depth[item.priority]--
+	more := len(q.h) > 0
+	q.l.Unlock()
+	if more {
+		// 唤醒其他空闲的执行线程
+		q.notify()
+	}
+	return item.run, true
+}
+
+func (q *runQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// 关闭队列 返回所有排队中的任务
+func (q *runQueue) close() []*taskRun {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.closed = true
+	runs := make([]*taskRun, 0, len(q.h))
+	for len(q.h) > 0 {
+		runs = append(runs, heap.Pop(&q.h).(*runItem).run)
+	}
+	q.depth = make(map[task.Priority]int)
+	return runs
+}
+
+// 各优先级排队中的任务数
+func (q *runQueue) getDepth() map[task.Priority]int {
+	q.l.Lock()
+	defer q.l.Unlock()
+	m := make(map[task.Priority]int)
+	for p, n := range q.depth {
+		if n > 0 {
+			m[p] = n
+		}
+	}
+	return m
+}
+
+// 获取各优先级排队等待执行的任务数
+func (tt *TimedTask) GetQueueDepth() map[task.Priority]int {
+	return tt.queue.getDepth()
+}
@@ -0,0 +1,93 @@
+package GoTask
+
+import (
+	"gitee.com/magicianlyx/GoTask/task"
+	"testing"
+	"time"
+)
+
+func TestRunQueue(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	q := newRunQueue(time.Second)
+	push := func(key string, p task.Priority, at time.Time) {
+		ti := task.NewTaskInfo(key, nil, task.NewSpecSchedule(time.Minute)).WithOptions(&task.Options{Priority: p})
+		q.push(newTaskRun(ti), at)
+	}
+	push("bulk", task.PriorityLow, now)
+	push("report", task.PriorityNormal, now)
+	push("report2", task.PriorityNormal, now)
+	push("heartbeat", task.PriorityCritical, now.Add(100*time.Millisecond))
+	// 低优先级任务已等待超过3个老化时长 优先于刚入队的紧急任务
+	push("late", task.PriorityCritical, now.Add(3*time.Second+100*time.Millisecond))
+
+	depth := q.getDepth()
+	if depth[task.PriorityLow] != 1 || depth[task.PriorityNormal] != 2 || depth[task.PriorityCritical] != 2 {
+		t.Errorf("unexpected depth: %v", depth)
+	}
+	want := []string{"heartbeat", "report", "report2", "bulk", "late"}
+	for _, key := range want {
+		run, ok := q.pop()
+		if !ok || run.ti.Key != key {
+			t.Fatalf("want %s, got %v", key, run)
+		}
+	}
+	if _, ok := q.pop(); ok || len(q.getDepth()) != 0 {
+		t.Errorf("queue should be empty")
+	}
+
+	push("a", task.PriorityNormal, now)
+	if runs := q.close(); len(runs) != 1 || q.push(runs[0], now) {
+		t.Errorf("closed queue should reject runs")
+	}
+}
+
+func TestTimedTask_QueuePriority(t *testing.T) {
+	_, tt := newFakeClockTimedTask(t, nil)
+	started, release := make(chan struct{}), make(chan struct{})
+	tt.Add("blocker", func() (map[string]interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}, task.NewSpecSchedule(time.Hour))
+	order := make(chan string, 3)
+	for _, c := range []struct {
+		key string
+		p   task.Priority
+	}{{"bulk", task.PriorityLow}, {"report", task.PriorityNormal}, {"heartbeat", task.PriorityCritical}} {
+		key := c.key
+		tt.Add(key, func() (map[string]interface{}, error) {
+			order <- key
+			return nil, nil
+		}, task.NewSpecSchedule(time.Hour), &task.Options{Priority: c.p})
+	}
+
+	// 唯一的执行线程被占用时 其余任务按优先级排队
+	tt.Execute("blocker")
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("blocker not started")
+	}
+	tt.Execute("bulk")
+	tt.Execute("report")
+	tt.Execute("heartbeat")
+	depth := tt.GetQueueDepth()
+	if len(depth) != 3 || depth[task.PriorityLow] != 1 || depth[task.PriorityNormal] != 1 || depth[task.PriorityCritical] != 1 {
+		t.Errorf("unexpected depth: %v", depth)
+	}
+
+	close(release)
+	for _, want := range []string{"heartbeat", "report", "bulk"} {
+		select {
+		case key := <-order:
+			if key != want {
+				t.Errorf("got %s, want %s", key, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not executed", want)
+		}
+	}
+	if depth := tt.GetQueueDepth(); len(depth) != 0 {
+		t.Errorf("queue should be empty: %v", depth)
+	}
+}
@@ -0,0 +1,143 @@
+package GoTask
+
+import (
+	"context"
+	"gitee.com/magicianlyx/GoTask/clock"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/task"
+	"gitee.com/magicianlyx/GoTask/trace"
+	"sync"
+)
+
+// 关闭时未完成的一次执行
+type AbandonedRun struct {
+	*task.TaskInfo
+	Attempt int  // 第几次尝试 首次执行为1
+	Started bool // 是否已开始执行 为true时说明截止时仍在执行 为false时说明尚未派发就被丢弃
+}
+
+// 调度器关闭报告
+type ShutdownReport struct {
+	Abandoned []*AbandonedRun // 未完成的执行
+}
+
+// 调度器关闭状态
+type shutdownState struct {
+	once      sync.Once
+	done      chan struct{}            // 所有线程退出后关闭
+	l         sync.Mutex               // 保护dropped及retries
+	dropped   []*AbandonedRun          // 关闭后未能派发而被丢弃的执行
+	retries   map[*taskRun]clock.Timer // 等待中的重试
+	executing sync.Map                 // 执行中的任务 map[*taskRun]pool.GoroutineUID
+}
+
+func newShutdownState() *shutdownState {
+	return &shutdownState{
+		done:    make(chan struct{}),
+		retries: make(map[*taskRun]clock.Timer),
+	}
+}
+
+// 优雅关闭调度器 不再发射新的调度 等待执行中的任务结束
+// ctx到期时停止等待 取消所有任务context 并在报告中列出未完成的执行 可重复调用
+func (tt *TimedTask) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	tt.ss.once.Do(func() {
+		close(tt.shutdownIssueSign)
+		close(tt.shutdownExecutorSign)
+		for _, run := range tt.queue.close() {
+			tt.abandon(run)
+		}
+		tt.stopRetries()
+		go func() {
+			tt.wg.Wait()
+			close(tt.ss.done)
+		}()
+	})
+
+	var err error
+	select {
+	case <-tt.ss.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	tt.cancelCtx()
+	return tt.shutdownReport(), err
+}
+
+// 是否已开始关闭
+func (tt *TimedTask) isShutdown() bool {
+	select {
+	case <-tt.shutdownExecutorSign:
+		return true
+	default:
+		return false
+	}
+}
+
+// 将一次执行派发给执行线程 调度器关闭后丢弃
+func (tt *TimedTask) send(run *taskRun) {
+	if tt.isShutdown() {
+		tt.abandon(run)
+		return
+	}
+	run.queued = tt.clock.Now()
+	tt.traceEnqueue(run)
+	if !tt.queue.push(run, run.queued) {
+		tt.abandon(run)
+	}
+}
+
+// 记录一次被丢弃的执行
+func (tt *TimedTask) abandon(run *taskRun) {
+	tt.traceSchedule(run, trace.DecisionAbandon, 0, 0)
+	tt.ss.l.Lock()
+	tt.ss.dropped = append(tt.ss.dropped, &AbandonedRun{TaskInfo: run.ti, Attempt: run.attempt})
+	tt.ss.l.Unlock()
+}
+
+// 重试到期后移除记录
+func (tt *TimedTask) delRetry(run *taskRun) {
+	tt.ss.l.Lock()
+	delete(tt.ss.retries, run)
+	tt.ss.l.Unlock()
+}
+
+// 停止所有等待中的重试 并记录为被丢弃的执行
+func (tt *TimedTask) stopRetries() {
+	tt.ss.l.Lock()
+	defer tt.ss.l.Unlock()
+	for run, timer := range tt.ss.retries {
+		if timer.Stop() {
+			tt.traceSchedule(run, trace.DecisionAbandon, 0, 0)
+			tt.ss.dropped = append(tt.ss.dropped, &AbandonedRun{TaskInfo: run.ti, Attempt: run.attempt})
+		}
+		delete(tt.ss.retries, run)
+	}
+}
+
+// 生成关闭报告 包含被丢弃的执行、仍在执行的任务及排队等待的执行
+func (tt *TimedTask) shutdownReport() *ShutdownReport {
+	report := &ShutdownReport{Abandoned: make([]*AbandonedRun, 0)}
+	tt.ss.l.Lock()
+	report.Abandoned = append(report.Abandoned, tt.ss.dropped...)
+	tt.ss.l.Unlock()
+	tt.ss.executing.Range(func(k, v interface{}) bool {
+		run := k.(*taskRun)
+		report.Abandoned = append(report.Abandoned, &AbandonedRun{TaskInfo: run.ti, Attempt: run.attempt, Started: true})
+		return true
+	})
+	for _, run := range tt.guard.pendingRuns() {
+		report.Abandoned = append(report.Abandoned, &AbandonedRun{TaskInfo: run.ti, Attempt: run.attempt})
+	}
+	return report
+}
+
+// 标记任务开始执行
+func (tt *TimedTask) startExecuting(run *taskRun, gid pool.GoroutineUID) {
+	tt.ss.executing.Store(run, gid)
+}
+
+// 标记任务执行结束
+func (tt *TimedTask) endExecuting(run *taskRun) {
+	tt.ss.executing.Delete(run)
+}
@@ -0,0 +1,238 @@
+package GoTask
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 默认每个key参与统计的最近执行次数
+const DefaultStatsWindow = 1000
+
+// 默认执行时长直方图的桶上界
+var DefaultDurationBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// 一组时长的统计
+type DurationSummary struct {
+	Min time.Duration
+	Max time.Duration
+	Avg time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// 执行时长直方图 包含所有执行
+type DurationHistogram struct {
+	Buckets []time.Duration // 桶上界 升序
+	Counts  []int64         // 执行时长不大于对应桶上界的执行次数（累计值）
+	Sum     time.Duration   // 执行时长总和
+	Count   int64           // 总执行次数
+}
+
+// 单个key的执行统计 时长统计只包含最近Options.StatsWindow次执行
+type RunStats struct {
+	Count     int64              // 总执行次数
+	Failures  int64              // 执行失败次数
+	Duration  DurationSummary    // 执行时长
+	Lag       DurationSummary    // 开始执行时刻与计划时刻的差值 重试时为与重试到期时刻的差值
+	Histogram *DurationHistogram // 执行时长直方图
+}
+
+// 调度器全局计数
+type Counters struct {
+	Fires    int64 // 按调度计划触发的次数 不包含主动执行及重试
+	Runs     int64 // 执行次数 包含重试
+	Failures int64 // 执行失败次数
+	Bans     int64 // 禁止key的次数
+	Misfires int64 // 因错过而被丢弃的调度次数
+}
+
+// 调度器全局计数（原子操作）
+type counters struct {
+	fires    int64
+	runs     int64
+	failures int64
+	bans     int64
+	misfires int64
+}
+
+func (c *counters) get() *Counters {
+	return &Counters{
+		Fires:    atomic.LoadInt64(&c.fires),
+		Runs:     atomic.LoadInt64(&c.runs),
+		Failures: atomic.LoadInt64(&c.failures),
+		Bans:     atomic.LoadInt64(&c.bans),
+		Misfires: atomic.LoadInt64(&c.misfires),
+	}
+}
+
+// 定长时长样本 满时覆盖最早的样本
+type sampleWindow struct {
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newSampleWindow(size int) *sampleWindow {
+	return &sampleWindow{samples: make([]time.Duration, 0, size)}
+}
+
+func (w *sampleWindow) add(d time.Duration) {
+	if !w.full {
+		w.samples = append(w.samples, d)
+		if len(w.samples) == cap(w.samples) {
+			w.full = true
+		}
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+}
+
+func (w *sampleWindow) summary() DurationSummary {
+	n := len(w.samples)
+	if n == 0 {
+		return DurationSummary{}
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return DurationSummary{
+		Min: sorted[0],
+		Max: sorted[n-1],
+		Avg: sum / time.Duration(n),
+		P95: percentile(sorted, 0.95),
+		P99: percentile(sorted, 0.99),
+	}
+}
+
+// 已排序样本的百分位数（最近秩法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.999999) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// 单个key的执行统计（线程安全）
+type runStats struct {
+	l         sync.Mutex
+	count     int64
+	failures  int64
+	durations *sampleWindow
+	lags      *sampleWindow
+	bounds    []time.Duration
+	buckets   []int64
+	sum       time.Duration
+}
+
+func newRunStats(window int, bounds []time.Duration) *runStats {
+	return &runStats{
+		l:         sync.Mutex{},
+		durations: newSampleWindow(window),
+		lags:      newSampleWindow(window),
+		bounds:    bounds,
+		buckets:   make([]int64, len(bounds)),
+	}
+}
+
+func (s *runStats) add(duration, lag time.Duration, failed bool) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.count++
+	if failed {
+		s.failures++
+	}
+	s.durations.add(duration)
+	s.lags.add(lag)
+	s.sum += duration
+	for i, b := range s.bounds {
+		if duration <= b {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (s *runStats) get() *RunStats {
+	s.l.Lock()
+	defer s.l.Unlock()
+	counts := make([]int64, len(s.buckets))
+	copy(counts, s.buckets)
+	return &RunStats{
+		Count:    s.count,
+		Failures: s.failures,
+		Duration: s.durations.summary(),
+		Lag:      s.lags.summary(),
+		Histogram: &DurationHistogram{
+			Buckets: s.bounds,
+			Counts:  counts,
+			Sum:     s.sum,
+			Count:   s.count,
+		},
+	}
+}
+
+// 记录一次执行的时长及延迟
+func (tt *TimedTask) recordStats(key string, duration, lag time.Duration, err error) {
+	atomic.AddInt64(&tt.counters.runs, 1)
+	if err != nil {
+		atomic.AddInt64(&tt.counters.failures, 1)
+	}
+	s, ok := tt.stats.Load(key)
+	if !ok {
+		s, _ = tt.stats.LoadOrStore(key, newRunStats(tt.o.StatsWindow, tt.o.DurationBuckets))
+	}
+	s.(*runStats).add(duration, lag, err != nil)
+}
+
+// 获取调度器全局计数
+func (tt *TimedTask) GetCounters() *Counters {
+	return tt.counters.get()
+}
+
+// 获取当前被禁止的key数
+func (tt *TimedTask) GetBanCount() int {
+	return tt.bMap.Len()
+}
+
+// 获取key的执行统计 未执行过或任务已被取消、禁止时返回false
+func (tt *TimedTask) GetRunStats(key string) (*RunStats, bool) {
+	s, ok := tt.stats.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return s.(*runStats).get(), true
+}
+
+// 获取所有key的执行统计
+func (tt *TimedTask) GetAllRunStats() map[string]*RunStats {
+	m := make(map[string]*RunStats)
+	tt.stats.Range(func(key, value interface{}) bool {
+		m[key.(string)] = value.(*runStats).get()
+		return true
+	})
+	return m
+}
@@ -0,0 +1,110 @@
+package GoTask
+
+import (
+	"errors"
+	"gitee.com/magicianlyx/GoTask/task"
+	"testing"
+	"time"
+)
+
+func TestSampleWindow_Summary(t *testing.T) {
+	w := newSampleWindow(100)
+	if s := w.summary(); s != (DurationSummary{}) {
+		t.Errorf("empty window should have zero summary, got %+v", s)
+	}
+	for i := 200; i >= 1; i-- {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	// 只保留最近的100个样本 1ms~100ms
+	want := DurationSummary{
+		Min: time.Millisecond,
+		Max: 100 * time.Millisecond,
+		Avg: 50500 * time.Microsecond,
+		P95: 95 * time.Millisecond,
+		P99: 99 * time.Millisecond,
+	}
+	if s := w.summary(); s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+}
+
+func TestTimedTask_GetRunStats(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	args := make(chan *task.ExecuteCbArgs, 1)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		args <- a
+	})
+	tt.Add("job", func() (map[string]interface{}, error) {
+		fc.Advance(10 * time.Millisecond)
+		return nil, nil
+	}, task.NewSpecSchedule(time.Minute))
+
+	for i := 1; i <= 2; i++ {
+		fc.BlockUntil(1)
+		lag := time.Duration(i) * time.Millisecond
+		fc.Set(fakeClockStart.Add(time.Duration(i)*time.Minute + lag))
+		select {
+		case a := <-args:
+			if a.Duration != 10*time.Millisecond || a.Lag != lag {
+				t.Errorf("run %d: duration %v lag %v", i, a.Duration, a.Lag)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("run %d not fired", i)
+		}
+	}
+
+	s, ok := tt.GetRunStats("job")
+	if !ok {
+		t.Fatal("stats not recorded")
+	}
+	if s.Count != 2 || s.Duration.Max != 10*time.Millisecond || s.Lag.Min != time.Millisecond || s.Lag.Max != 2*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	if _, ok := tt.GetRunStats("missing"); ok {
+		t.Errorf("missing key should have no stats")
+	}
+	if len(tt.GetAllRunStats()) != 1 {
+		t.Errorf("unexpected all stats")
+	}
+
+	// 任务被清除后统计一并删除 全局计数不受影响
+	tt.Cancel("job")
+	if _, ok := tt.GetRunStats("job"); ok || len(tt.GetAllRunStats()) != 0 || tt.GetCounters().Runs != 2 {
+		t.Errorf("stats should be removed with the task")
+	}
+}
+
+func TestTimedTask_RetryLag(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	args := make(chan *task.ExecuteCbArgs, 2)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		args <- a
+	})
+	fail := errors.New("fail")
+	tt.Add("job", func() (map[string]interface{}, error) {
+		return nil, fail
+	}, task.NewSpecSchedule(time.Hour), &task.Options{Retry: task.NewFixedRetryPolicy(2, time.Minute)})
+
+	fc.BlockUntil(1)
+	fired := fakeClockStart.Add(time.Hour + time.Millisecond)
+	fc.Set(fired)
+	// 调度计时器及重试计时器
+	fc.BlockUntil(2)
+	fc.Set(fired.Add(time.Minute + 2*time.Millisecond))
+	// 回调异步执行 按尝试次数核对 重试的延迟以重试到期时刻为基准 不包含退避时长
+	lags := map[int]time.Duration{1: time.Millisecond, 2: 2 * time.Millisecond}
+	for i := 0; i < 2; i++ {
+		select {
+		case a := <-args:
+			if want, ok := lags[a.Attempt]; !ok || a.Lag != want {
+				t.Errorf("attempt %d: lag %v, want %v", a.Attempt, a.Lag, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("attempt %d not executed", i+1)
+		}
+	}
+	s, ok := tt.GetRunStats("job")
+	if !ok || s.Count != 2 || s.Failures != 2 || s.Lag.Min != time.Millisecond || s.Lag.Max != 2*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"bufio"
+	"errors"
+	jsoniter "github.com/json-iterator/go"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrStoreIsClosed = errors.New("job store is closed")
+)
+
+// 默认追加多少条操作后压缩日志文件
+const DefaultCompactEvery = 1000
+
+const (
+	logFileName = "jobs.log"
+
+	opSave      = "save"
+	opDelete    = "delete"
+	opBan       = "ban"
+	opDeleteBan = "unban"
+)
+
+// 日志中的单条操作
+type logEntry struct {
+	Op  string     `json:"op"`
+	Key string     `json:"key"`
+	Job *JobRecord `json:"job,omitempty"`
+}
+
+// 基于本地目录的任务存储（线程安全）
+// 所有操作以JSON行追加写入日志文件 打开时及每追加一定条数后重放日志并压缩为当前状态的快照
+// 追加写入只刷新到操作系统缓冲而不调用fsync 系统崩溃时可能丢失上次压缩后的操作 仅压缩后的快照保证落盘
+type FileJobStore struct {
+	l            sync.Mutex
+	dir          string
+	f            *os.File
+	w            *bufio.Writer
+	jobs         map[string]*JobRecord
+	bans         map[string]struct{} // 被禁止的key
+	appends      int                 // 上次压缩后追加的操作数
+	compactEvery int                 // 追加多少条操作后压缩 小于等于0时只在打开及调用Compact时压缩
+}
+
+func NewFileJobStore(dir string) (*FileJobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &FileJobStore{
+		dir:          dir,
+		jobs:         make(map[string]*JobRecord),
+		bans:         make(map[string]struct{}),
+		compactEvery: DefaultCompactEvery,
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileJobStore) path() string {
+	return filepath.Join(s.dir, logFileName)
+}
+
+// 重放日志 恢复当前状态 末尾不完整的行会被忽略
+func (s *FileJobStore) replay() error {
+	f, err := os.Open(s.path())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		e := &logEntry{}
+		if err := jsoniter.Unmarshal(scanner.Bytes(), e); err != nil {
+			continue
+		}
+		s.apply(e)
+	}
+	return scanner.Err()
+}
+
+func (s *FileJobStore) apply(e *logEntry) {
+	switch e.Op {
+	case opSave:
+		if e.Job != nil {
+			s.jobs[e.Key] = e.Job
+		}
+	case opDelete:
+		delete(s.jobs, e.Key)
+	case opBan:
+		s.bans[e.Key] = struct{}{}
+	case opDeleteBan:
+		delete(s.bans, e.Key)
+	}
+}
+
+// 将当前状态写入新日志文件并替换旧日志
+func (s *FileJobStore) compact() error {
+	tmp := s.path() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, key := range s.sortedJobKeys() {
+		if err := writeEntry(w, &logEntry{Op: opSave, Key: key, Job: s.jobs[key]}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	for _, key := range s.sortedBanKeys() {
+		if err := writeEntry(w, &logEntry{Op: opBan, Key: key}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path()); err != nil {
+		return err
+	}
+
+	if s.f != nil {
+		s.f.Close()
+	}
+	s.f, err = os.OpenFile(s.path(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	s.w = bufio.NewWriter(s.f)
+	s.appends = 0
+	return nil
+}
+
+func writeEntry(w *bufio.Writer, e *logEntry) error {
+	bs, err := jsoniter.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(bs); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// 追加一条操作 只刷新到操作系统缓冲 不调用fsync
+func (s *FileJobStore) append(e *logEntry) error {
+	if s.f == nil {
+		return ErrStoreIsClosed
+	}
+	if err := writeEntry(s.w, e); err != nil {
+		return err
+	}
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	s.apply(e)
+	s.appends++
+	if s.compactEvery > 0 && s.appends >= s.compactEvery {
+		return s.compact()
+	}
+	return nil
+}
+
+// 设置追加多少条操作后自动压缩日志文件 小于等于0时不自动压缩
+func (s *FileJobStore) SetCompactEvery(n int) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.compactEvery = n
+}
+
+func (s *FileJobStore) sortedJobKeys() []string {
+	keys := make([]string, 0, len(s.jobs))
+	for key := range s.jobs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *FileJobStore) sortedBanKeys() []string {
+	keys := make([]string, 0, len(s.bans))
+	for key := range s.bans {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *FileJobStore) Save(rec *JobRecord) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.append(&logEntry{Op: opSave, Key: rec.Key, Job: rec.Clone()})
+}
+
+func (s *FileJobStore) Delete(key string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if _, ok := s.jobs[key]; !ok {
+		return nil
+	}
+	return s.append(&logEntry{Op: opDelete, Key: key})
+}
+
+func (s *FileJobStore) SaveBan(key string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if _, ok := s.bans[key]; ok {
+		return nil
+	}
+	return s.append(&logEntry{Op: opBan, Key: key})
+}
+
+func (s *FileJobStore) DeleteBan(key string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if _, ok := s.bans[key]; !ok {
+		return nil
+	}
+	return s.append(&logEntry{Op: opDeleteBan, Key: key})
+}
+
+func (s *FileJobStore) Load() ([]*JobRecord, []string, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	jobs := make([]*JobRecord, 0, len(s.jobs))
+	for _, key := range s.sortedJobKeys() {
+		jobs = append(jobs, s.jobs[key].Clone())
+	}
+	return jobs, s.sortedBanKeys(), nil
+}
+
+// 压缩日志文件
+func (s *FileJobStore) Compact() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.f == nil {
+		return ErrStoreIsClosed
+	}
+	return s.compact()
+}
+
+func (s *FileJobStore) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.w.Flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f = nil
+	return err
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"gitee.com/magicianlyx/GoTask/task"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileJobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotask-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry := task.NewRegistry()
+	registry.Register("sync", func() (map[string]interface{}, error) {
+		return nil, nil
+	})
+	ti, err := registry.NewTaskInfo("job", "sync", task.NewSpecSchedule(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ti.WithOptions(&task.Options{Timeout: time.Second, Overlap: task.OverlapSkip})
+	ti.Update()
+
+	s, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := NewJobRecord(ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{s.Save(rec), s.SaveBan("a"), s.SaveBan("b"), s.DeleteBan("a"), s.Close()} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err = NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	jobs, bans, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || len(bans) != 1 || bans[0] != "b" {
+		t.Fatalf("unexpected state: %d jobs, bans %v", len(jobs), bans)
+	}
+	rti, err := jobs[0].Restore(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sche, _ := task.EncodeSchedule(rti.Sche); rti.Count != 1 || !rti.NextTime.Equal(ti.NextTime) || sche.Spec != time.Minute {
+		t.Errorf("state not restored: count=%d next=%v", rti.Count, rti.NextTime)
+	}
+	if o := rti.GetOptions(); o.Timeout != time.Second || o.Overlap != task.OverlapSkip {
+		t.Errorf("options not restored: %+v", o)
+	}
+}
+
+func TestFileJobStore_AutoCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotask-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetCompactEvery(4)
+	rec := &JobRecord{Key: "job", Name: "sync"}
+	for i := 0; i < 10; i++ {
+		rec.Count = i
+		if err := s.Save(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, logFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 压缩后的1条快照 加上之后追加的2条操作
+	if lines := strings.Count(string(bs), "\n"); lines != 3 {
+		t.Errorf("log should be compacted, got %d lines", lines)
+	}
+	jobs, _, _ := s.Load()
+	if len(jobs) != 1 || jobs[0].Count != 9 {
+		t.Errorf("unexpected state after compaction: %+v", jobs)
+	}
+}
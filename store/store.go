@@ -0,0 +1,92 @@
+package store
+
+import (
+	"gitee.com/magicianlyx/GoTask/task"
+	"time"
+)
+
+// 持久化的任务记录 包含任务定义及执行状态
+type JobRecord struct {
+	Key      string             `json:"key"`
+	Name     string             `json:"name"` // 任务方法在注册表中的名称
+	Schedule *task.ScheduleSpec `json:"schedule"`
+	Options  *task.Options      `json:"options,omitempty"`
+	AddTime  time.Time          `json:"addTime"`
+	LastTime time.Time          `json:"lastTime"`
+	NextTime time.Time          `json:"nextTime"`
+	Count    int                `json:"count"`
+	Misfired int                `json:"misfired"`
+	HasNext  bool               `json:"hasNext"`
+	Paused   bool               `json:"paused,omitempty"`
+	PausedAt time.Time          `json:"pausedAt,omitempty"`
+}
+
+// 任务持久化存储接口
+type JobStore interface {
+	Save(rec *JobRecord) error             // 保存任务记录 同key时覆盖
+	Delete(key string) error               // 删除任务记录
+	SaveBan(key string) error              // 保存被禁止的key
+	DeleteBan(key string) error            // 删除被禁止的key
+	Load() ([]*JobRecord, []string, error) // 加载所有任务记录及被禁止的key
+	Close() error
+}
+
+// 根据任务信息生成任务记录 任务未命名或调度计划不可序列化时返回错误
+func NewJobRecord(ti *task.TaskInfo) (*JobRecord, error) {
+	if ti.Name == "" {
+		return nil, task.ErrTaskNameNotRegistered
+	}
+	sche, err := task.EncodeSchedule(ti.Sche)
+	if err != nil {
+		return nil, err
+	}
+	return &JobRecord{
+		Key:      ti.Key,
+		Name:     ti.Name,
+		Schedule: sche,
+		Options:  ti.Options.Clone(),
+		AddTime:  ti.AddTime,
+		LastTime: ti.LastTime,
+		NextTime: ti.NextTime,
+		Count:    ti.Count,
+		Misfired: ti.Misfired,
+		HasNext:  ti.HasNext,
+		Paused:   ti.Paused,
+		PausedAt: ti.PausedTime,
+	}, nil
+}
+
+// 根据注册表还原任务信息 执行状态与记录保持一致
+func (r *JobRecord) Restore(registry *task.Registry) (*task.TaskInfo, error) {
+	sche, err := r.Schedule.Decode()
+	if err != nil {
+		return nil, err
+	}
+	ti, err := registry.NewTaskInfo(r.Key, r.Name, sche)
+	if err != nil {
+		return nil, err
+	}
+	ti.WithOptions(r.Options)
+	ti.AddTime = r.AddTime
+	ti.LastTime = r.LastTime
+	ti.NextTime = r.NextTime
+	ti.Count = r.Count
+	ti.Misfired = r.Misfired
+	ti.HasNext = r.HasNext
+	ti.Paused = r.Paused
+	ti.PausedTime = r.PausedAt
+	return ti, nil
+}
+
+func (r *JobRecord) Clone() *JobRecord {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	c.Options = r.Options.Clone()
+	if r.Schedule != nil {
+		s := *r.Schedule
+		c.Schedule = &s
+	}
+	return &c
+}
@@ -4,9 +4,12 @@ import (
 	"sync"
 )
 
+// 定长队列（线程安全） 队列已满时压入元素会挤出最早的元素
 type Queue struct {
 	l    sync.RWMutex
-	list []interface{}
+	list []interface{} // 环形缓冲区
+	head int           // 最早元素的下标
+	n    int           // 元素个数
 	size int
 }
 
@@ -21,48 +24,91 @@ func NewQueue(size int) *Queue {
 	}
 }
 
+// 依次压入元素 队列已满时挤出最早的元素
 func (q *Queue) Push(v ...interface{}) {
 	q.l.Lock()
 	defer q.l.Unlock()
-	if len(q.list) >= q.size {
-		q.list = q.list[1:]
+	for _, o := range v {
+		if q.n < q.size {
+			q.list[(q.head+q.n)%q.size] = o
+			q.n++
+		} else {
+			q.list[q.head] = o
+			q.head = (q.head + 1) % q.size
+		}
 	}
-	q.list = append(q.list, v)
 }
 
+// 弹出最早的元素 队列为空时返回nil
 func (q *Queue) Pop() interface{} {
 	q.l.Lock()
 	defer q.l.Unlock()
 
-	if len(q.list) >= 1 {
-		o := q.list[0]
-		q.list = q.list[1:]
+	if q.n >= 1 {
+		o := q.list[q.head]
+		q.list[q.head] = nil
+		q.head = (q.head + 1) % q.size
+		q.n--
 		return o
 	} else {
 		return nil
 	}
 }
 
+// 获取最早的元素 队列为空时返回nil
 func (q *Queue) Peek() interface{} {
 	q.l.RLock()
 	defer q.l.RUnlock()
-	if len(q.list) >= 1 {
-		return q.list[0]
+	if q.n >= 1 {
+		return q.list[q.head]
 	} else {
 		return nil
 	}
 }
 
+// 元素个数
+func (q *Queue) Len() int {
+	q.l.RLock()
+	defer q.l.RUnlock()
+	return q.n
+}
+
+// 所有元素 从早到晚排列
+func (q *Queue) GetAll() []interface{} {
+	q.l.RLock()
+	defer q.l.RUnlock()
+	list := make([]interface{}, q.n)
+	for i := 0; i < q.n; i++ {
+		list[i] = q.list[(q.head+i)%q.size]
+	}
+	return list
+}
+
+// 最近的limit个元素 从晚到早排列 limit不大于0时返回所有元素
+func (q *Queue) Latest(limit int) []interface{} {
+	q.l.RLock()
+	defer q.l.RUnlock()
+	if limit <= 0 || limit > q.n {
+		limit = q.n
+	}
+	list := make([]interface{}, limit)
+	for i := 0; i < limit; i++ {
+		list[i] = q.list[(q.head+q.n-1-i)%q.size]
+	}
+	return list
+}
+
 func (q *Queue) Clone() *Queue {
 	list := make([]interface{}, q.size)
 	q.l.RLock()
-	for i := range q.list {
-		list[i] = q.list[i]
-	}
+	copy(list, q.list)
+	head, n := q.head, q.n
 	q.l.RUnlock()
 	return &Queue{
 		l:    sync.RWMutex{},
 		list: list,
+		head: head,
+		n:    n,
 		size: q.size,
 	}
 }
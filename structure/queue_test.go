@@ -0,0 +1,33 @@
+package structure
+
+import "testing"
+
+func TestQueue(t *testing.T) {
+	q := NewQueue(3)
+	q.Push(1, 2)
+	q.Push(3, 4)
+	if q.Len() != 3 || q.Peek() != 2 {
+		t.Fatalf("unexpected queue: len %d peek %v", q.Len(), q.Peek())
+	}
+	all := q.GetAll()
+	if len(all) != 3 || all[0] != 2 || all[2] != 4 {
+		t.Errorf("unexpected elements: %v", all)
+	}
+	latest := q.Latest(2)
+	if len(latest) != 2 || latest[0] != 4 || latest[1] != 3 {
+		t.Errorf("unexpected latest: %v", latest)
+	}
+
+	c := q.Clone()
+	if q.Pop() != 2 || q.Len() != 2 {
+		t.Errorf("pop should remove the earliest element")
+	}
+	if c.Len() != 3 || c.Peek() != 2 {
+		t.Errorf("clone should not be affected")
+	}
+	q.Pop()
+	q.Pop()
+	if q.Pop() != nil || q.Len() != 0 {
+		t.Errorf("queue should be empty")
+	}
+}
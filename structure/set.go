@@ -25,3 +25,12 @@ func (s *Set) Add(key interface{}) {
 func (s *Set) Delete(key interface{}) {
 	s.s.Delete(key)
 }
+
+func (s *Set) Len() int {
+	n := 0
+	s.s.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
@@ -0,0 +1,287 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCronExpression = errors.New("invalid cron expression")
+)
+
+// cron表达式各字段取值范围
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+	endNames map[string]uint // 作为范围结尾时的名称取值 覆盖names
+}
+
+var (
+	cronSecond = cronBounds{0, 59, nil, nil}
+	cronMinute = cronBounds{0, 59, nil, nil}
+	cronHour   = cronBounds{0, 23, nil, nil}
+	cronDom    = cronBounds{1, 31, nil, nil}
+	cronMonth  = cronBounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}, nil}
+	// 周字段允许7表示周日 解析后合并到0
+	cronDow = cronBounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}, map[string]uint{"sun": 7}}
+)
+
+// 预定义宏
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// 最多向后查找的年数 超出视为表达式永不触发
+const cronSearchYears = 5
+
+// cron表达式调度器 支持5位（分 时 日 月 周）及6位（秒 分 时 日 月 周）表达式
+type CronSchedule struct {
+	expr   string
+	second uint64
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	domAll bool // 日字段为*
+	dowAll bool // 周字段为*
+}
+
+func NewCronSchedule(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "@") {
+		m, ok := cronMacros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown macro `%s`", ErrCronExpression, spec)
+		}
+		spec = m
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: expected 5 or 6 fields, got %d in `%s`", ErrCronExpression, len(fields), expr)
+	}
+
+	c := &CronSchedule{expr: expr}
+	var err error
+	if c.second, err = parseCronField(fields[0], cronSecond); err != nil {
+		return nil, err
+	}
+	if c.minute, err = parseCronField(fields[1], cronMinute); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[2], cronHour); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[3], cronDom); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[4], cronMonth); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[5], cronDow); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) > 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domAll = isCronWildcard(fields[3])
+	c.dowAll = isCronWildcard(fields[5])
+	return c, nil
+}
+
+func isCronWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// 解析单个字段 返回取值位图
+func parseCronField(field string, b cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		v, err := parseCronPart(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= v
+	}
+	return bits, nil
+}
+
+// 解析形如 `*` `*/5` `1-10/2` `MON-FRI` `3` 的单项
+func parseCronPart(part string, b cronBounds) (uint64, error) {
+	rangeStep := strings.Split(part, "/")
+	if len(rangeStep) > 2 {
+		return 0, fmt.Errorf("%w: too many slashes in `%s`", ErrCronExpression, part)
+	}
+
+	var start, end uint
+	step := uint(1)
+	lowAndHigh := strings.Split(rangeStep[0], "-")
+	switch {
+	case isCronWildcard(lowAndHigh[0]) && len(lowAndHigh) == 1:
+		start, end = b.min, b.max
+	case len(lowAndHigh) == 1 || len(lowAndHigh) == 2:
+		var err error
+		if start, err = parseCronValue(lowAndHigh[0], b, false); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = parseCronValue(lowAndHigh[1], b, true); err != nil {
+				return 0, err
+			}
+		} else if len(rangeStep) == 2 {
+			// `3/5` 表示从3开始每5个单位
+			end = b.max
+		}
+	default:
+		return 0, fmt.Errorf("%w: too many hyphens in `%s`", ErrCronExpression, part)
+	}
+
+	if len(rangeStep) == 2 {
+		s, err := strconv.Atoi(rangeStep[1])
+		if err != nil || s <= 0 {
+			return 0, fmt.Errorf("%w: invalid step `%s`", ErrCronExpression, part)
+		}
+		step = uint(s)
+	}
+	if start < b.min || end > b.max || start > end {
+		return 0, fmt.Errorf("%w: `%s` out of range [%d, %d]", ErrCronExpression, part, b.min, b.max)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, nil
+}
+
+// isEnd为true时s为范围结尾 优先使用endNames
+func parseCronValue(s string, b cronBounds, isEnd bool) (uint, error) {
+	if isEnd && b.endNames != nil {
+		if v, ok := b.endNames[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	if b.names != nil {
+		if v, ok := b.names[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%w: invalid value `%s`", ErrCronExpression, s)
+	}
+	return uint(v), nil
+}
+
+// 日期是否满足日及周字段 两者都被限定时满足任一即可
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) > 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) > 0
+	if c.domAll || c.dowAll {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// 计算t之后（不含t）的下一次触发时刻
+func (c *CronSchedule) Next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + cronSearchYears
+
+	added := false
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}, false
+	}
+
+	for c.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !c.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for c.second&(1<<uint(t.Second())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+	return t, true
+}
+
+// 以最后一次调度的计划时刻为基准计算下次执行时刻 未调度过时以添加时刻为基准
+// 落后于计划时刻时由任务的错过执行策略决定如何处理
+func (c *CronSchedule) Expression(t *TaskInfo) (nt time.Time, isValid bool) {
+	base := t.AddTime
+	if t.Count > 0 && !t.NextTime.IsZero() {
+		base = t.NextTime
+	}
+	return c.Next(base.In(time.Local))
+}
+
+func (c *CronSchedule) ToString() string {
+	return c.expr
+}
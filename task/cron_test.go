@@ -0,0 +1,62 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	base := time.Date(2020, 1, 1, 10, 30, 15, 0, time.Local) // 周三
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2020, 1, 1, 10, 31, 0, 0, time.Local)},
+		{"*/10 * * * * *", time.Date(2020, 1, 1, 10, 30, 20, 0, time.Local)},
+		{"0 */2 * * *", time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)},
+		{"15,45 9-17 * * MON-FRI", time.Date(2020, 1, 1, 10, 45, 0, 0, time.Local)},
+		{"0 0 * * SAT", time.Date(2020, 1, 4, 0, 0, 0, 0, time.Local)},
+		{"0 0 1 FEB *", time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.Local)},
+		{"0 0 13 * 5", time.Date(2020, 1, 3, 0, 0, 0, 0, time.Local)},
+		{"@hourly", time.Date(2020, 1, 1, 11, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)},
+		{"@weekly", time.Date(2020, 1, 5, 0, 0, 0, 0, time.Local)},
+		{"@yearly", time.Date(2021, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"0 9 * * 1-7", time.Date(2020, 1, 2, 9, 0, 0, 0, time.Local)},
+		{"0 9 * * 5-7", time.Date(2020, 1, 3, 9, 0, 0, 0, time.Local)},
+		{"0 9 * * */7", time.Date(2020, 1, 5, 9, 0, 0, 0, time.Local)},
+		{"0 0 12 * * MON-SUN", time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)},
+	}
+	for _, c := range cases {
+		s, err := NewCronSchedule(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		got, ok := s.Next(base)
+		if !ok || !got.Equal(c.want) {
+			t.Errorf("%s: got %v want %v", c.expr, got, c.want)
+		}
+		if s.ToString() != c.expr {
+			t.Errorf("%s: ToString got %s", c.expr, s.ToString())
+		}
+	}
+}
+
+func TestCronSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "@every", "5-1 * * * *", "* * * FOO *"} {
+		if _, err := NewCronSchedule(expr); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
+
+func TestCronSchedule_Never(t *testing.T) {
+	s, err := NewCronSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Next(time.Now()); ok {
+		t.Errorf("30 Feb should never fire")
+	}
+}
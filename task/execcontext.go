@@ -0,0 +1,126 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 使用任务执行上下文的任务方法
+type ExecTaskObj func(ec *ExecContext) (map[string]interface{}, error)
+
+// 将使用任务执行上下文的任务方法适配为支持context的任务方法
+// ctx中没有任务执行上下文时 使用只包含ctx及空暂存区的上下文
+func WrapExecTaskObj(obj ExecTaskObj) ContextTaskObj {
+	if obj == nil {
+		return nil
+	}
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		ec, ok := FromContext(ctx)
+		if !ok {
+			ec = &ExecContext{Scratch: NewScratch()}
+		}
+		// 使用传入的ctx 保证超时及取消信号生效
+		ec = ec.clone()
+		ec.Context = ctx
+		return obj(ec)
+	}
+}
+
+// 任务执行上下文 可直接作为context.Context使用
+type ExecContext struct {
+	context.Context
+	Key           string                 // 任务key
+	Attempt       int                    // 本次执行是第几次尝试 首次执行为1
+	Count         int                    // 任务调度次数（包含本次）
+	ScheduledTime time.Time              // 本次执行的计划时刻 主动执行时为time.Time{}
+	FireTime      time.Time              // 本次执行实际触发的时刻
+	PrevResult    *TaskResult            // 上一次执行的结果 未执行过时为nil
+	Upstream      map[string]*TaskResult // 工作流节点的上游节点执行结果 非工作流执行时为nil
+	Scratch       *Scratch               // 同一key多次执行间共享的暂存区
+}
+
+func (ec *ExecContext) clone() *ExecContext {
+	c := *ec
+	return &c
+}
+
+// 触发延迟 实际触发时刻与计划时刻的差值
+func (ec *ExecContext) Lag() time.Duration {
+	if ec.ScheduledTime.IsZero() {
+		return 0
+	}
+	return ec.FireTime.Sub(ec.ScheduledTime)
+}
+
+type execContextKey struct{}
+
+// 返回携带任务执行上下文的context
+func WithExecContext(ctx context.Context, ec *ExecContext) context.Context {
+	ec = ec.clone()
+	ec.Context = ctx
+	return context.WithValue(ctx, execContextKey{}, ec)
+}
+
+// 从context中获取任务执行上下文
+func FromContext(ctx context.Context) (*ExecContext, bool) {
+	if ec, ok := ctx.(*ExecContext); ok {
+		return ec, true
+	}
+	ec, ok := ctx.Value(execContextKey{}).(*ExecContext)
+	return ec, ok
+}
+
+// 键值暂存区 线程安全 用于在同一key的多次执行间保存状态（如增量同步的高水位）
+// 只保存在内存中 任务被清除时一同清除
+type Scratch struct {
+	l sync.RWMutex
+	m map[string]interface{}
+}
+
+func NewScratch() *Scratch {
+	return &Scratch{
+		l: sync.RWMutex{},
+		m: make(map[string]interface{}),
+	}
+}
+
+func (s *Scratch) Get(key string) (interface{}, bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+func (s *Scratch) Set(key string, value interface{}) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.m[key] = value
+}
+
+func (s *Scratch) Delete(key string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	delete(s.m, key)
+}
+
+// 在锁内读取并修改key对应的值 f返回的值将被写回
+func (s *Scratch) Update(key string, f func(old interface{}, ok bool) interface{}) interface{} {
+	s.l.Lock()
+	defer s.l.Unlock()
+	old, ok := s.m[key]
+	v := f(old, ok)
+	s.m[key] = v
+	return v
+}
+
+// 所有键值的副本
+func (s *Scratch) GetAll() map[string]interface{} {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	m := make(map[string]interface{}, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+	return m
+}
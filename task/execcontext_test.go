@@ -0,0 +1,38 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWrapExecTaskObj(t *testing.T) {
+	scheduled := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	scratch := NewScratch()
+	scratch.Set("mark", 1)
+	obj := WrapExecTaskObj(func(ec *ExecContext) (map[string]interface{}, error) {
+		v, _ := ec.Scratch.Get("mark")
+		return map[string]interface{}{"key": ec.Key, "lag": ec.Lag(), "mark": v, "err": ec.Err()}, nil
+	})
+
+	ctx, cancel := context.WithCancel(WithExecContext(context.Background(), &ExecContext{
+		Key:           "sync",
+		ScheduledTime: scheduled,
+		FireTime:      scheduled.Add(time.Second),
+		Scratch:       scratch,
+	}))
+	cancel()
+	res, _ := obj(ctx)
+	if res["key"] != "sync" || res["lag"] != time.Second || res["mark"] != 1 {
+		t.Errorf("unexpected exec context: %v", res)
+	}
+	if res["err"] != context.Canceled {
+		t.Errorf("exec context should use the derived ctx, got %v", res["err"])
+	}
+
+	// 没有任务执行上下文时使用空暂存区
+	res, _ = obj(context.Background())
+	if res["key"] != "" || res["mark"] != nil {
+		t.Errorf("unexpected default exec context: %v", res)
+	}
+}
@@ -0,0 +1,67 @@
+package task
+
+import "time"
+
+// 调度器落后于计划时刻（错过执行）时的处理策略
+type MisfirePolicy int
+
+const (
+	MisfireFireAll  MisfirePolicy = 0 // 依次补执行所有错过的调度
+	MisfireFireOnce MisfirePolicy = 1 // 立即执行一次 之后丢弃其余错过的调度 重新对齐到计划时刻
+	MisfireSkip     MisfirePolicy = 2 // 丢弃所有错过的调度 等待下一个计划时刻
+)
+
+// 默认错过执行阈值 延迟不超过该时长时视为正常执行
+const DefaultMisfireThreshold = time.Second
+
+// 跳过调度时最多连续跳过的次数 防止间隔过小的调度陷入长时间循环
+const maxSkipCount = 1 << 20
+
+func (p MisfirePolicy) ToString() string {
+	switch p {
+	case MisfireFireOnce:
+		return "fire_once"
+	case MisfireSkip:
+		return "skip"
+	default:
+		return "fire_all"
+	}
+}
+
+func (p MisfirePolicy) IsValid() bool {
+	return p >= MisfireFireAll && p <= MisfireSkip
+}
+
+// 跳过一次调度 不执行任务 只调整下次执行时间
+func (t *TaskInfo) Skip() {
+	t.Count += 1
+	t.Misfired += 1
+	t.NextTime, t.HasNext = t.Sche.Expression(t)
+}
+
+// 跳过所有计划时刻不晚于now的调度 返回跳过的次数
+func (t *TaskInfo) skipUntil(now time.Time) int {
+	n := 0
+	for t.HasNext && !t.NextTime.After(now) && n < maxSkipCount {
+		t.Skip()
+		n++
+	}
+	return n
+}
+
+// 到达计划时刻时调用 按错过执行策略调整任务信息
+// 返回本次是否需要执行任务 以及被丢弃的调度次数
+func (t *TaskInfo) Fire(now time.Time) (fire bool, dropped int) {
+	o := t.GetOptions()
+	if o.Misfire == MisfireFireAll || now.Sub(t.NextTime) <= o.MisfireThreshold {
+		t.Update()
+		return true, 0
+	}
+	switch o.Misfire {
+	case MisfireFireOnce:
+		t.Update()
+		return true, t.skipUntil(now)
+	default:
+		return false, t.skipUntil(now)
+	}
+}
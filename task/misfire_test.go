@@ -0,0 +1,65 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskInfo_Fire(t *testing.T) {
+	spec := 10 * time.Millisecond
+	cases := []struct {
+		policy  MisfirePolicy
+		fire    bool
+		dropped int
+		count   int
+	}{
+		{MisfireFireAll, true, 0, 1},
+		{MisfireFireOnce, true, 4, 5},
+		{MisfireSkip, false, 5, 5},
+	}
+	for _, c := range cases {
+		ti := NewTaskInfo("k", nil, NewSpecSchedule(spec)).WithOptions(&Options{
+			Misfire:          c.policy,
+			MisfireThreshold: time.Millisecond,
+		})
+		// 调度器落后了 5.5 个周期
+		now := ti.AddTime.Add(55 * time.Millisecond)
+		fire, dropped := ti.Fire(now)
+		if fire != c.fire || dropped != c.dropped || ti.Count != c.count {
+			t.Errorf("%s: got fire=%v dropped=%d count=%d", c.policy.ToString(), fire, dropped, ti.Count)
+		}
+		if c.policy != MisfireFireAll && !ti.NextTime.After(now) {
+			t.Errorf("%s: next time %v should be realigned after %v", c.policy.ToString(), ti.NextTime, now)
+		}
+		if ti.Misfired != c.dropped {
+			t.Errorf("%s: misfired %d", c.policy.ToString(), ti.Misfired)
+		}
+	}
+}
+
+func TestTaskInfo_Resume(t *testing.T) {
+	for _, policy := range []PausePolicy{PauseSkip, PauseReplay} {
+		ti := NewTaskInfo("k", nil, NewSpecSchedule(10*time.Millisecond)).WithOptions(&Options{Pause: policy})
+		next := ti.NextTime
+		ti.Pause(ti.AddTime)
+		if !ti.Paused || !ti.Clone().Paused {
+			t.Fatalf("%s: task should be paused", policy.ToString())
+		}
+		// 暂停了 5.5 个周期
+		now := ti.AddTime.Add(55 * time.Millisecond)
+		dropped := ti.Resume(now)
+		if ti.Paused || !ti.PausedTime.IsZero() {
+			t.Errorf("%s: task should be resumed", policy.ToString())
+		}
+		switch policy {
+		case PauseSkip:
+			if dropped != 5 || !ti.NextTime.After(now) {
+				t.Errorf("skip: dropped %d next %v", dropped, ti.NextTime)
+			}
+		case PauseReplay:
+			if dropped != 0 || !ti.NextTime.Equal(next) || ti.Count != 0 {
+				t.Errorf("replay: dropped %d next %v count %d", dropped, ti.NextTime, ti.Count)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package task
+
+import "time"
+
+// 任务配置
+type Options struct {
+	Timeout  time.Duration `json:"timeout"`  // 单次执行超时时长 超时后执行线程不再等待任务返回 0为不限制
+	Retry    *RetryPolicy  `json:"retry"`    // 执行失败时的重试策略 为空时不重试
+	Overlap  OverlapPolicy `json:"overlap"`  // 上次执行未结束时再次触发的处理策略
+	Priority Priority      `json:"priority"` // 执行线程繁忙时的执行优先级
+
+	Misfire          MisfirePolicy `json:"misfire"`          // 错过执行时的处理策略
+	MisfireThreshold time.Duration `json:"misfireThreshold"` // 延迟超过该时长才视为错过执行 默认DefaultMisfireThreshold
+
+	Pause PausePolicy `json:"pause"` // 恢复暂停的任务时 暂停期间错过的调度的处理策略
+}
+
+// 构建默认配置
+func NewDefaultOptions() *Options {
+	return &Options{
+		Timeout:  0,
+		Overlap:  OverlapAllow,
+		Priority: PriorityNormal,
+
+		Misfire:          MisfireFireAll,
+		MisfireThreshold: DefaultMisfireThreshold,
+
+		Pause: PauseSkip,
+	}
+}
+
+// 填充参数
+func (o *Options) fillDefaultOptions() {
+	if o.Timeout < 0 {
+		o.Timeout = 0
+	}
+	if !o.Overlap.IsValid() {
+		o.Overlap = OverlapAllow
+	}
+	if !o.Priority.IsValid() {
+		o.Priority = PriorityNormal
+	}
+	if !o.Misfire.IsValid() {
+		o.Misfire = MisfireFireAll
+	}
+	if o.MisfireThreshold <= 0 {
+		o.MisfireThreshold = DefaultMisfireThreshold
+	}
+	if !o.Pause.IsValid() {
+		o.Pause = PauseSkip
+	}
+}
+
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	return &Options{
+		Timeout:  o.Timeout,
+		Retry:    o.Retry.Clone(),
+		Overlap:  o.Overlap,
+		Priority: o.Priority,
+
+		Misfire:          o.Misfire,
+		MisfireThreshold: o.MisfireThreshold,
+
+		Pause: o.Pause,
+	}
+}
@@ -0,0 +1,25 @@
+package task
+
+// 同一任务上次执行未结束时 再次触发的处理策略
+type OverlapPolicy int
+
+const (
+	OverlapAllow OverlapPolicy = 0 // 允许并发执行
+	OverlapSkip  OverlapPolicy = 1 // 跳过本次触发 并通知执行回调
+	OverlapQueue OverlapPolicy = 2 // 排队等待上次执行结束后再执行 最多排队一次 多余的触发将被跳过
+)
+
+func (p OverlapPolicy) ToString() string {
+	switch p {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	default:
+		return "allow"
+	}
+}
+
+func (p OverlapPolicy) IsValid() bool {
+	return p >= OverlapAllow && p <= OverlapQueue
+}
@@ -0,0 +1,45 @@
+package task
+
+import "time"
+
+// 暂停期间错过的调度在恢复时的处理策略
+type PausePolicy int
+
+const (
+	PauseSkip   PausePolicy = 0 // 丢弃暂停期间错过的调度 等待下一个计划时刻
+	PauseReplay PausePolicy = 1 // 保留暂停期间错过的调度 恢复后按错过执行策略补执行
+)
+
+func (p PausePolicy) ToString() string {
+	switch p {
+	case PauseReplay:
+		return "replay"
+	default:
+		return "skip"
+	}
+}
+
+func (p PausePolicy) IsValid() bool {
+	return p >= PauseSkip && p <= PauseReplay
+}
+
+// 暂停任务 暂停期间任务不会被调度 执行状态及调度计划保持不变
+func (t *TaskInfo) Pause(now time.Time) {
+	t.Paused = true
+	t.PausedTime = now
+}
+
+// 恢复任务 按暂停策略处理暂停期间错过的调度 返回被丢弃的调度次数
+func (t *TaskInfo) Resume(now time.Time) int {
+	t.Paused = false
+	t.PausedTime = time.Time{}
+	return t.CatchUp(now, t.GetOptions().Pause)
+}
+
+// 按策略处理计划时刻不晚于now的调度 返回被丢弃的调度次数
+func (t *TaskInfo) CatchUp(now time.Time, policy PausePolicy) int {
+	if policy == PauseReplay {
+		return 0
+	}
+	return t.skipUntil(now)
+}
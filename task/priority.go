@@ -0,0 +1,28 @@
+package task
+
+// 任务优先级 执行线程繁忙时优先执行优先级高的任务
+type Priority int
+
+const (
+	PriorityLow      Priority = -1
+	PriorityNormal   Priority = 0
+	PriorityHigh     Priority = 1
+	PriorityCritical Priority = 2
+)
+
+func (p Priority) ToString() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+func (p Priority) IsValid() bool {
+	return p >= PriorityLow && p <= PriorityCritical
+}
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrTaskNameNotRegistered = errors.New("task name is not registered")
+)
+
+// 任务方法注册表（线程安全） 以名称引用任务方法 使任务定义可以被持久化
+type Registry struct {
+	m sync.Map // map[string]ContextTaskObj
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sync.Map{}}
+}
+
+// 注册任务方法 同名时覆盖
+func (r *Registry) Register(name string, obj TaskObj) {
+	r.m.Store(name, WrapTaskObj(obj))
+}
+
+// 注册支持context的任务方法 同名时覆盖
+func (r *Registry) RegisterContext(name string, obj ContextTaskObj) {
+	r.m.Store(name, obj)
+}
+
+// 注销任务方法
+func (r *Registry) Unregister(name string) {
+	r.m.Delete(name)
+}
+
+// 获取任务方法
+func (r *Registry) Get(name string) (ContextTaskObj, bool) {
+	if v, ok := r.m.Load(name); ok {
+		return v.(ContextTaskObj), true
+	}
+	return nil, false
+}
+
+// 根据注册的任务方法创建任务信息对象
+func (r *Registry) NewTaskInfo(key string, name string, sche ISchedule) (*TaskInfo, error) {
+	obj, ok := r.Get(name)
+	if !ok {
+		return nil, ErrTaskNameNotRegistered
+	}
+	ti := NewContextTaskInfo(key, obj, sche)
+	ti.Name = name
+	return ti, nil
+}
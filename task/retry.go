@@ -0,0 +1,90 @@
+package task
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// 失败重试策略
+// Retryable无法序列化 持久化时不会保存 从Store恢复后为空 即所有错误均可重试
+// 需要按错误过滤重试时 恢复后应通过Set重新设置重试策略
+type RetryPolicy struct {
+	MaxAttempts int                  `json:"maxAttempts"` // 最大尝试次数（包含首次执行） 小于等于1时不重试
+	Backoff     time.Duration        `json:"backoff"`     // 首次重试前等待时长
+	MaxBackoff  time.Duration        `json:"maxBackoff"`  // 重试等待时长上限 0为不限制
+	Multiplier  float64              `json:"multiplier"`  // 指数退避倍数 小于等于1时为固定间隔退避
+	Jitter      float64              `json:"jitter"`      // 随机抖动比例 取值[0,1] 实际等待时长在 d*(1-Jitter) ~ d*(1+Jitter) 之间
+	Retryable   func(err error) bool `json:"-"`           // 判断错误是否可重试 为空时所有错误均可重试
+}
+
+// 固定间隔重试策略
+func NewFixedRetryPolicy(maxAttempts int, backoff time.Duration) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+	}
+}
+
+// 指数退避重试策略
+func NewExponentialRetryPolicy(maxAttempts int, backoff, maxBackoff time.Duration, multiplier float64) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+		Multiplier:  multiplier,
+	}
+}
+
+// 第attempt次尝试失败后 是否还需要重试
+func (p *RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	if p == nil || err == nil {
+		return false
+	}
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if p.Retryable != nil && !p.Retryable(err) {
+		return false
+	}
+	return true
+}
+
+// 第attempt次尝试失败后 下次重试前的等待时长
+func (p *RetryPolicy) NextBackoff(attempt int) time.Duration {
+	if p == nil || p.Backoff <= 0 {
+		return 0
+	}
+	// 未设置上限时以time.Duration的最大值为上限 避免高次指数退避溢出
+	limit := float64(math.MaxInt64)
+	if p.MaxBackoff > 0 {
+		limit = float64(p.MaxBackoff)
+	}
+	d := float64(p.Backoff)
+	if p.Multiplier > 1 && attempt > 1 {
+		d = d * math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	d = math.Min(d, limit)
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		d = d * (1 - jitter + 2*jitter*rand.Float64())
+	}
+	if d >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) Clone() *RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	return &RetryPolicy{
+		MaxAttempts: p.MaxAttempts,
+		Backoff:     p.Backoff,
+		MaxBackoff:  p.MaxBackoff,
+		Multiplier:  p.Multiplier,
+		Jitter:      p.Jitter,
+		Retryable:   p.Retryable,
+	}
+}
@@ -0,0 +1,33 @@
+package task
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_NextBackoff(t *testing.T) {
+	cases := []struct {
+		name    string
+		p       *RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"fixed", NewFixedRetryPolicy(3, time.Second), 5, time.Second},
+		{"exponential", NewExponentialRetryPolicy(5, time.Second, 0, 2), 3, 4 * time.Second},
+		{"max backoff", NewExponentialRetryPolicy(5, time.Second, 3*time.Second, 2), 3, 3 * time.Second},
+		// 未设置上限时 高次退避不应溢出为负数
+		{"overflow", NewExponentialRetryPolicy(200, time.Second, 0, 2), 100, time.Duration(math.MaxInt64)},
+	}
+	for _, c := range cases {
+		if got := c.p.NextBackoff(c.attempt); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	p := NewExponentialRetryPolicy(200, time.Second, 0, 2)
+	p.Jitter = 0.5
+	if got := p.NextBackoff(100); got <= 0 {
+		t.Errorf("jittered backoff overflowed: %v", got)
+	}
+}
@@ -109,9 +109,9 @@ func NewEveryDaySchedule(hour, minute, second, mSecond int) *EveryDaySchedule {
 }
 
 func (e *EveryDaySchedule) Expression(t *TaskInfo) (nt time.Time, isValid bool) {
-	now := time.Now()
+	now := t.Now()
 	nt = time.Date(now.Year(), now.Month(), now.Day(), e.hour, e.minute, e.second, e.mSecond, time.Local)
-	if nt.UnixNano() < now.UnixNano() {
+	if !nt.After(now) {
 		return nt.AddDate(0, 0, 1), true
 	}
 	return nt, true
@@ -0,0 +1,68 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrScheduleNotSerializable = errors.New("schedule is not serializable")
+)
+
+const (
+	ScheduleTypeSpec     = "spec"
+	ScheduleTypeSpecTime = "spec_time"
+	ScheduleTypePlan     = "plan"
+	ScheduleTypeEveryDay = "every_day"
+	ScheduleTypeCron     = "cron"
+)
+
+// 可序列化的调度计划描述 用于持久化
+type ScheduleSpec struct {
+	Type    string        `json:"type"`
+	Spec    time.Duration `json:"spec,omitempty"`
+	Time    int           `json:"time,omitempty"`
+	TList   []time.Time   `json:"tList,omitempty"`
+	Hour    int           `json:"hour,omitempty"`
+	Minute  int           `json:"minute,omitempty"`
+	Second  int           `json:"second,omitempty"`
+	MSecond int           `json:"mSecond,omitempty"`
+	Expr    string        `json:"expr,omitempty"`
+}
+
+// 将内置调度计划转换为可序列化的描述 自定义调度计划返回ErrScheduleNotSerializable
+func EncodeSchedule(sche ISchedule) (*ScheduleSpec, error) {
+	switch s := sche.(type) {
+	case *SpecSchedule:
+		return &ScheduleSpec{Type: ScheduleTypeSpec, Spec: s.spec}, nil
+	case *SpecTimeSchedule:
+		return &ScheduleSpec{Type: ScheduleTypeSpecTime, Spec: s.spec, Time: s.time}, nil
+	case *PlanSchedule:
+		return &ScheduleSpec{Type: ScheduleTypePlan, TList: s.tList}, nil
+	case *EveryDaySchedule:
+		return &ScheduleSpec{Type: ScheduleTypeEveryDay, Hour: s.hour, Minute: s.minute, Second: s.second, MSecond: s.mSecond}, nil
+	case *CronSchedule:
+		return &ScheduleSpec{Type: ScheduleTypeCron, Expr: s.expr}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrScheduleNotSerializable, sche)
+	}
+}
+
+// 还原调度计划
+func (s *ScheduleSpec) Decode() (ISchedule, error) {
+	switch s.Type {
+	case ScheduleTypeSpec:
+		return NewSpecSchedule(s.Spec), nil
+	case ScheduleTypeSpecTime:
+		return NewSpecTimeSchedule(s.Spec, s.Time), nil
+	case ScheduleTypePlan:
+		return NewPlanSchedule(s.TList), nil
+	case ScheduleTypeEveryDay:
+		return NewEveryDaySchedule(s.Hour, s.Minute, s.Second, s.MSecond), nil
+	case ScheduleTypeCron:
+		return NewCronSchedule(s.Expr)
+	default:
+		return nil, fmt.Errorf("%w: unknown type `%s`", ErrScheduleNotSerializable, s.Type)
+	}
+}
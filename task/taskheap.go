@@ -0,0 +1,91 @@
+package task
+
+import (
+	"container/heap"
+	"time"
+)
+
+// 下次执行时间索引项
+type heapItem struct {
+	key   string
+	next  time.Time
+	index int
+}
+
+// 按下次执行时间排序的最小堆 非线程安全
+// 只索引还有下一次执行且未暂停的任务 插入、删除、更新均为O(log n)
+type taskHeap struct {
+	items []*heapItem
+	index map[string]*heapItem
+}
+
+func newTaskHeap() *taskHeap {
+	return &taskHeap{
+		items: make([]*heapItem, 0),
+		index: make(map[string]*heapItem),
+	}
+}
+
+func (h *taskHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *taskHeap) Less(i, j int) bool {
+	return h.items[i].next.Before(h.items[j].next)
+}
+
+func (h *taskHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	item := x.(*heapItem)
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *taskHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	item.index = -1
+	return item
+}
+
+// 插入或更新任务的索引 任务没有下一次执行或已暂停时移除索引
+func (h *taskHeap) update(t *TaskInfo) {
+	item, ok := h.index[t.Key]
+	if !t.HasNext || t.Paused {
+		if ok {
+			h.remove(t.Key)
+		}
+		return
+	}
+	if ok {
+		item.next = t.NextTime
+		heap.Fix(h, item.index)
+		return
+	}
+	item = &heapItem{key: t.Key, next: t.NextTime}
+	h.index[t.Key] = item
+	heap.Push(h, item)
+}
+
+// 移除任务的索引
+func (h *taskHeap) remove(key string) {
+	if item, ok := h.index[key]; ok {
+		heap.Remove(h, item.index)
+		delete(h.index, key)
+	}
+}
+
+// 最早执行的任务key
+func (h *taskHeap) peek() (string, bool) {
+	if len(h.items) == 0 {
+		return "", false
+	}
+	return h.items[0].key, true
+}
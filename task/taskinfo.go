@@ -1,12 +1,27 @@
 package task
 
 import (
+	"context"
+	"gitee.com/magicianlyx/GoTask/clock"
 	"gitee.com/magicianlyx/GoTask/pool"
 	"time"
 )
 
 type TaskObj func() (map[string]interface{}, error)
 
+// 支持context的任务方法 任务被取消、禁止或调度器停止时ctx会被取消
+type ContextTaskObj func(ctx context.Context) (map[string]interface{}, error)
+
+// 将普通任务方法适配为支持context的任务方法
+func WrapTaskObj(obj TaskObj) ContextTaskObj {
+	if obj == nil {
+		return nil
+	}
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		return obj()
+	}
+}
+
 type TaskResult struct {
 	Result map[string]interface{}
 	Err    error
@@ -23,16 +38,23 @@ func (tr *TaskResult) Clone() *TaskResult {
 }
 
 type TaskInfo struct {
-	Key        string      // 任务标志key
-	Task       TaskObj     // 任务方法
-	LastTime   time.Time   // 最后一次执行任务的时间（未执行过时为time.Time{}）
-	AddTime    time.Time   // 任务添加的时间
-	NextTime   time.Time   // 下次执行时间
-	Count      int         // 任务执行次数
-	Sche       ISchedule   // 任务计划
-	HasNext    bool        // 是否还有下一次执行
-	LastResult *TaskResult // 任务最后一次执行的结果
-	timer      TimerObj    // 计时器
+	Key        string         // 任务标志key
+	Name       string         // 任务方法在注册表中的名称 非空时任务可被持久化
+	Task       TaskObj        // 任务方法
+	CtxTask    ContextTaskObj // 支持context的任务方法 非空时优先于Task执行
+	LastTime   time.Time      // 最后一次执行任务的时间（未执行过时为time.Time{}）
+	AddTime    time.Time      // 任务添加的时间
+	NextTime   time.Time      // 下次执行时间
+	Count      int            // 任务调度次数（包含因错过执行被跳过的调度）
+	Misfired   int            // 因错过执行被跳过的调度次数
+	Sche       ISchedule      // 任务计划
+	HasNext    bool           // 是否还有下一次执行
+	Paused     bool           // 是否已暂停 暂停期间不会被调度
+	PausedTime time.Time      // 暂停的时间（未暂停时为time.Time{}）
+	LastResult *TaskResult    // 任务最后一次执行的结果
+	Options    *Options       // 任务配置
+	timer      TimerObj       // 计时器
+	clock      clock.Clock    // 时钟
 }
 
 // 生成副本
@@ -42,14 +64,21 @@ func (t *TaskInfo) Clone() *TaskInfo {
 	}
 	rt := &TaskInfo{}
 	rt.Key = t.Key
+	rt.Name = t.Name
 	rt.Task = t.Task
+	rt.CtxTask = t.CtxTask
 	rt.LastTime = t.LastTime
 	rt.AddTime = t.AddTime
 	rt.NextTime = t.NextTime
 	rt.Count = t.Count
+	rt.Misfired = t.Misfired
 	rt.Sche = t.Sche
 	rt.HasNext = t.HasNext
+	rt.Paused = t.Paused
+	rt.PausedTime = t.PausedTime
 	rt.LastResult = t.LastResult.Clone()
+	rt.Options = t.Options.Clone()
+	rt.clock = t.clock
 	return rt
 }
 
@@ -76,8 +105,45 @@ func (t *TaskInfo) NextScheduleTime() time.Time {
 // 返回是否还有下一次执行
 func (t *TaskInfo) Update() {
 	t.Count += 1
-	t.LastTime = time.Now()
+	t.LastTime = t.Now()
+	t.NextTime, t.HasNext = t.Sche.Expression(t)
+}
+
+// 按任务使用的时钟获取当前时间
+func (t *TaskInfo) Now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+	return t.clock.Now()
+}
+
+// 设置任务使用的时钟 不修改任务状态
+func (t *TaskInfo) SetClock(c clock.Clock) {
+	t.clock = c
+}
+
+// 设置任务使用的时钟 以该时钟的当前时间重新作为添加时间 返回自身
+// 只应在任务被调度前调用
+func (t *TaskInfo) WithClock(c clock.Clock) *TaskInfo {
+	t.clock = c
+	t.AddTime = t.Now()
 	t.NextTime, t.HasNext = t.Sche.Expression(t)
+	return t
+}
+
+// 开始计时 按任务使用的时钟计时
+func (t *TaskInfo) StartTimer() {
+	t.timer = TimerWithClock(clock.OrDefault(t.clock))
+}
+
+// 结束计时 返回执行时长及起止时刻 未开始计时时返回零值
+func (t *TaskInfo) StopTimer() (spec time.Duration, start, end time.Time) {
+	if t.timer == nil {
+		return 0, time.Time{}, time.Time{}
+	}
+	spec, start, end = t.timer()
+	t.timer = nil
+	return
 }
 
 // 是否还有下一次执行
@@ -85,6 +151,35 @@ func (t *TaskInfo) HasNextExecute() bool {
 	return t.HasNext
 }
 
+// 设置任务配置 取第一个非空配置 返回自身
+func (t *TaskInfo) WithOptions(options ...*Options) *TaskInfo {
+	for _, o := range options {
+		if o != nil {
+			o = o.Clone()
+			o.fillDefaultOptions()
+			t.Options = o
+			break
+		}
+	}
+	return t
+}
+
+// 获取任务配置 未设置时返回默认配置
+func (t *TaskInfo) GetOptions() *Options {
+	if t.Options == nil {
+		return NewDefaultOptions()
+	}
+	return t.Options
+}
+
+// 执行任务方法
+func (t *TaskInfo) Run(ctx context.Context) (map[string]interface{}, error) {
+	if t.CtxTask != nil {
+		return t.CtxTask(ctx)
+	}
+	return t.Task()
+}
+
 // 创建一个任务信息对象
 func NewTaskInfo(key string, task TaskObj, sche ISchedule) *TaskInfo {
 	now := time.Now()
@@ -100,11 +195,28 @@ func NewTaskInfo(key string, task TaskObj, sche ISchedule) *TaskInfo {
 	return ti
 }
 
+// 创建一个支持context的任务信息对象
+func NewContextTaskInfo(key string, task ContextTaskObj, sche ISchedule) *TaskInfo {
+	ti := NewTaskInfo(key, nil, sche)
+	ti.CtxTask = task
+	return ti
+}
+
 type ExecuteCbArgs struct {
 	*TaskInfo
-	Res   map[string]interface{}
-	Error error
-	Gid   pool.GoroutineUID
+	Res      map[string]interface{}
+	Error    error
+	Gid      pool.GoroutineUID
+	Attempt  int           // 本次执行是第几次尝试 首次执行为1
+	Final    bool          // 是否为本次调度的最后一次尝试 为false时说明执行失败且稍后会重试
+	Duration time.Duration // 执行时长
+	Lag      time.Duration // 开始执行时刻与计划时刻的差值 重试时为与重试到期时刻的差值 主动执行时为0
+}
+
+type MisfireCbArgs struct {
+	*TaskInfo
+	Dropped int  // 本次被丢弃的调度次数
+	Fired   bool // 本次是否仍执行了一次任务
 }
 
 type AddCbArgs struct {
@@ -123,3 +235,10 @@ type BanCbArgs struct {
 }
 
 type UnBanCbArgs BanCbArgs
+
+type PauseCbArgs struct {
+	Key   string
+	Error error
+}
+
+type ResumeCbArgs PauseCbArgs
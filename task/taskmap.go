@@ -6,38 +6,71 @@ import (
 )
 
 // 任务字典 线程安全
+// 字典中存储的任务信息不会被原地修改 修改时总是写入新的副本
+// 同时以最小堆索引各任务的下次执行时间 选择最早执行的任务为O(1) 修改为O(log n)
 type TaskMap struct {
+	l    sync.Mutex // 写锁 保证读-改-写操作及索引的一致性
 	tMap sync.Map
+	h    *taskHeap // 下次执行时间索引
 }
 
 func NewTaskMap() *TaskMap {
 	return &TaskMap{
+		l:    sync.Mutex{},
 		tMap: sync.Map{},
+		h:    newTaskHeap(),
 	}
 }
 
+func (tm *TaskMap) store(key string, task *TaskInfo) {
+	tm.tMap.Store(key, task)
+	tm.h.update(task)
+}
+
 // 添加
 func (tm *TaskMap) Add(key string, task *TaskInfo) {
+	tm.l.Lock()
+	defer tm.l.Unlock()
 	if !tm.IsExist(key) {
-		tm.tMap.Store(key, task)
+		tm.store(key, task)
 	}
 }
 
 // 存在时才修改
 func (tm *TaskMap) Set(key string, task *TaskInfo) {
+	tm.l.Lock()
+	defer tm.l.Unlock()
 	if tm.IsExist(key) {
-		tm.tMap.Store(key, task)
+		tm.store(key, task)
 	}
 }
 
 // 添加或修改
 func (tm *TaskMap) AddOrSet(key string, task *TaskInfo) {
-	tm.tMap.Store(key, task)
+	tm.l.Lock()
+	defer tm.l.Unlock()
+	tm.store(key, task)
+}
+
+// 存在时在副本上执行修改并写回 返回修改后的副本
+func (tm *TaskMap) Update(key string, f func(t *TaskInfo)) (*TaskInfo, bool) {
+	tm.l.Lock()
+	defer tm.l.Unlock()
+	t := tm.Get(key)
+	if t == nil {
+		return nil, false
+	}
+	f(t)
+	tm.store(key, t)
+	return t.Clone(), true
 }
 
 // 删除
 func (tm *TaskMap) Delete(key string) {
+	tm.l.Lock()
+	defer tm.l.Unlock()
 	tm.tMap.Delete(key)
+	tm.h.remove(key)
 }
 
 // 获取 返回副本
@@ -58,34 +91,26 @@ func (tm *TaskMap) IsExist(key string) bool {
 
 // 选择下一个最早执行的任务
 func (tm *TaskMap) SelectNextExec() (*TaskInfo, time.Duration, bool) {
-	var minv *TaskInfo
-	tm.tMap.Range(func(key, value interface{}) bool {
-		v, ok := value.(*TaskInfo)
-		if !ok {
-			return true
-		}
-		if !v.HasNext {
-			return true
-		}
-		if minv == nil {
-			minv = v
-			return true
-		}
-		mnt := minv.NextScheduleTime()
-		vnt := v.NextScheduleTime()
+	return tm.SelectNextExecAt(time.Now())
+}
 
-		if mnt.UnixNano() > vnt.UnixNano() {
-			minv = v
-		}
-		return true
-	})
+// 以now为当前时间 选择下一个最早执行的任务 返回距离执行的时长 已到期时为0
+func (tm *TaskMap) SelectNextExecAt(now time.Time) (*TaskInfo, time.Duration, bool) {
+	tm.l.Lock()
+	key, ok := tm.h.peek()
+	var minv *TaskInfo
+	if ok {
+		minv = tm.Get(key)
+	}
+	tm.l.Unlock()
 	if minv == nil {
 		return nil, 0, false
 	}
 	ns := minv.NextScheduleTime()
-	spec := ns.Sub(time.Now())
-	if spec <= 0 {
-		spec = time.Nanosecond
+	spec := ns.Sub(now)
+	if spec < 0 {
+		// 已到期 立即触发 伪造时钟不会触发未到期的定时器
+		spec = 0
 	}
 	return minv, spec, true
 }
@@ -0,0 +1,82 @@
+package task
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func newBenchTaskMap(n int) (*TaskMap, []string) {
+	tm := NewTaskMap()
+	keys := make([]string, n)
+	for i := 0; i < n; i++ {
+		keys[i] = fmt.Sprintf("task-%d", i)
+		spec := time.Duration(rand.Intn(3600)+1) * time.Second
+		tm.Add(keys[i], NewTaskInfo(keys[i], nil, NewSpecSchedule(spec)))
+	}
+	return tm, keys
+}
+
+// 遍历整个字典选择最早执行的任务 作为基准对比
+func selectNextExecByRange(tm *TaskMap) *TaskInfo {
+	var minv *TaskInfo
+	tm.tMap.Range(func(key, value interface{}) bool {
+		v := value.(*TaskInfo)
+		if v.HasNext && (minv == nil || v.NextTime.Before(minv.NextTime)) {
+			minv = v
+		}
+		return true
+	})
+	return minv.Clone()
+}
+
+func TestTaskMap_SelectNextExec(t *testing.T) {
+	tm, keys := newBenchTaskMap(1000)
+	for i := 0; i < 2000; i++ {
+		key := keys[rand.Intn(len(keys))]
+		switch rand.Intn(3) {
+		case 0:
+			tm.Update(key, func(t *TaskInfo) {
+				t.Update()
+			})
+		case 1:
+			tm.Delete(key)
+		default:
+			tm.AddOrSet(key, NewTaskInfo(key, nil, NewSpecTimeSchedule(time.Duration(rand.Intn(3600))*time.Second, rand.Intn(2))))
+		}
+		got, _, ok := tm.SelectNextExec()
+		want := selectNextExecByRange(tm)
+		if !ok || want == nil {
+			if ok != (want != nil) {
+				t.Fatalf("step %d: got ok=%v, want %v", i, ok, want)
+			}
+			continue
+		}
+		if !got.NextTime.Equal(want.NextTime) {
+			t.Fatalf("step %d: got %s at %v, want %s at %v", i, got.Key, got.NextTime, want.Key, want.NextTime)
+		}
+	}
+}
+
+func BenchmarkTaskMap_SelectNextExec_100k(b *testing.B) {
+	tm, _ := newBenchTaskMap(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ti, _, _ := tm.SelectNextExec()
+		tm.Update(ti.Key, func(t *TaskInfo) {
+			t.Update()
+		})
+	}
+}
+
+func BenchmarkTaskMap_SelectNextExecByRange_100k(b *testing.B) {
+	tm, _ := newBenchTaskMap(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ti := selectNextExecByRange(tm)
+		tm.Update(ti.Key, func(t *TaskInfo) {
+			t.Update()
+		})
+	}
+}
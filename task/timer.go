@@ -1,15 +1,23 @@
 package task
 
-import "time"
+import (
+	"gitee.com/magicianlyx/GoTask/clock"
+	"time"
+)
 
 type TimerObj func() (spec time.Duration, start, end time.Time)
 
 // 计时器
 func Timer() TimerObj {
-	p1 := time.Now()
+	return TimerWithClock(clock.NewRealClock())
+}
+
+// 使用指定时钟的计时器
+func TimerWithClock(c clock.Clock) TimerObj {
+	p1 := c.Now()
 	return func() (spec time.Duration, start, end time.Time) {
-		p2 := time.Now()
-		spec = time.Now().Sub(p1)
+		p2 := c.Now()
+		spec = p2.Sub(p1)
 		start = p1
 		end = p2
 		return
@@ -1,10 +1,13 @@
 package GoTask
 
 import (
+	"context"
+	"errors"
+	"gitee.com/magicianlyx/GoTask/clock"
 	"gitee.com/magicianlyx/GoTask/pool"
 	"gitee.com/magicianlyx/GoTask/structure"
 	"gitee.com/magicianlyx/GoTask/task"
-	"errors"
+	"gitee.com/magicianlyx/GoTask/trace"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +18,10 @@ var (
 	ErrTaskIsNotExist = errors.New("task is not exist")
 	ErrTaskIsBan      = errors.New("task is ban")
 	ErrTaskIsUnBan    = errors.New("task is already unban")
+	ErrTaskTimeout    = errors.New("task execute timeout")
+	ErrTaskSkipped    = errors.New("task is skipped because previous execution is still running")
+	ErrTaskIsPaused   = errors.New("task is already paused")
+	ErrTaskIsUnPaused = errors.New("task is not paused")
 )
 
 type addCallback func(*task.AddCbArgs)
@@ -22,58 +29,132 @@ type cancelCallback func(*task.CancelCbArgs)
 type executeCallback func(*task.ExecuteCbArgs)
 type banCallback func(*task.BanCbArgs)
 type unBanCallback func(*task.UnBanCbArgs)
+type misfireCallback func(*task.MisfireCbArgs)
+type workflowCallback func(*WorkflowCbArgs)
+type pauseCallback func(*task.PauseCbArgs)
+type resumeCallback func(*task.ResumeCbArgs)
 
 type TimedTask struct {
 	l                    sync.RWMutex
-	tMap                 *task.TaskMap       // 定时任务字典
-	bMap                 *structure.Set      // 被禁止添加执行的key
-	tasks                chan *task.TaskInfo // 即将被执行的任务通道
-	refreshSign          chan struct{}       // 刷新信号通知通道
-	singleValue          int64               // 保证同一时刻单刷新信号
-	shutdownExecutorSign chan struct{}       // 任务执行线程 停止信号通知通道
-	shutdownIssueSign    chan struct{}       // 任务发射线程 停止信号通知通道
+	tMap                 *task.TaskMap  // 定时任务字典
+	bMap                 *structure.Set // 被禁止添加执行的key
+	queue                *runQueue      // 等待执行的任务队列
+	refreshSign          chan struct{}  // 刷新信号通知通道
+	shutdownExecutorSign chan struct{}  // 任务执行线程 停止信号通知通道 关闭时关闭
+	shutdownIssueSign    chan struct{}  // 任务发射线程 停止信号通知通道 关闭时关闭
 	routineCount         int
 	addCallback          *CbFuncMap
 	cancelCallback       *CbFuncMap
 	executeCallback      *CbFuncMap
 	banCallback          *CbFuncMap
 	unBanCallback        *CbFuncMap
+	misfireCallback      *CbFuncMap
+	pauseCallback        *CbFuncMap
+	resumeCallback       *CbFuncMap
+	workflowCallback     *CbFuncMap
 	wg                   *sync.WaitGroup
+	ctx                  context.Context    // 调度器根context 停止时取消
+	cancelCtx            context.CancelFunc // 取消调度器根context
+	keyCtx               sync.Map           // 每个key对应的context key取消或禁止时取消 map[string]*keyContext
+	scratch              sync.Map           // 每个key的暂存区 任务被清除时删除 map[string]*task.Scratch
+	guard                *runGuard          // 同一key并发执行控制
+	clock                clock.Clock        // 时钟
+	o                    *Options           // 配置
+	ss                   *shutdownState     // 关闭状态
+	pausedAll            int64              // 0调度中 1全局暂停
+	pausedAllTime        time.Time          // 全局暂停的时间
+	workflowSeq          int64              // 工作流执行序号
+	workflows            sync.Map           // 已添加的工作流 工作流被取消或禁止时删除 map[string]*workflowEntry
+	history              sync.Map           // 每个key的执行记录 任务被取消或禁止时删除 map[string]*structure.Queue
+	globalHistory        *structure.Queue   // 全局执行记录 未开启时为nil
+	stats                sync.Map           // 每个key的执行统计 任务被取消或禁止时删除 map[string]*runStats
+	persistL             sync.Mutex         // 任务持久化锁 保证持久化按顺序写入最新状态
+	persisted            *structure.Set     // 任务存储中已有记录的key 由persistL保护
+	counters             counters           // 调度器全局计数
+}
+
+// 一次待执行的任务
+type taskRun struct {
+	ti      *task.TaskInfo // 调度时的任务信息副本
+	attempt int            // 第几次尝试 首次执行为1
+	step    *workflowStep  // 所属的工作流节点 非工作流执行时为nil
+
+	scheduled time.Time // 计划时刻 主动执行时为time.Time{}
+	due       time.Time // 重试到期时刻 首次尝试为time.Time{}
+	fired     time.Time // 实际触发时刻
+	queued    time.Time // 入队时刻
+}
+
+func newTaskRun(ti *task.TaskInfo) *taskRun {
+	return &taskRun{ti: ti, attempt: 1, fired: ti.Now()}
+}
+
+// 开始执行时刻与计划时刻的差值 重试时为与重试到期时刻的差值 主动执行时为0
+func (r *taskRun) lag(start time.Time) time.Duration {
+	if r.scheduled.IsZero() {
+		return 0
+	}
+	if !r.due.IsZero() {
+		return start.Sub(r.due)
+	}
+	return start.Sub(r.scheduled)
+}
+
+// 单个key的context
+type keyContext struct {
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewTimedTask(maxRoutineCount int) *TimedTask {
+	return NewTimedTaskWithOptions(&Options{RoutineCount: maxRoutineCount})
+}
+
+func NewTimedTaskWithOptions(options *Options) *TimedTask {
+	options = options.Clone()
+	options.fillDefaultOptions()
+	ctx, cancel := context.WithCancel(context.Background())
 	tt := &TimedTask{
-		sync.RWMutex{},
-		task.NewTaskMap(),
-		structure.NewSet(),
-		make(chan *task.TaskInfo),
-		make(chan struct{}),
-		0,
-		make(chan struct{}),
-		make(chan struct{}),
-		maxRoutineCount,
-		NewCbFuncMap(),
-		NewCbFuncMap(),
-		NewCbFuncMap(),
-		NewCbFuncMap(),
-		NewCbFuncMap(),
-		&sync.WaitGroup{},
+		l:                    sync.RWMutex{},
+		tMap:                 task.NewTaskMap(),
+		bMap:                 structure.NewSet(),
+		queue:                newRunQueue(options.PriorityAging),
+		refreshSign:          make(chan struct{}, 1),
+		shutdownExecutorSign: make(chan struct{}),
+		shutdownIssueSign:    make(chan struct{}),
+		routineCount:         options.RoutineCount,
+		addCallback:          NewCbFuncMap(),
+		cancelCallback:       NewCbFuncMap(),
+		executeCallback:      NewCbFuncMap(),
+		banCallback:          NewCbFuncMap(),
+		unBanCallback:        NewCbFuncMap(),
+		misfireCallback:      NewCbFuncMap(),
+		pauseCallback:        NewCbFuncMap(),
+		resumeCallback:       NewCbFuncMap(),
+		workflowCallback:     NewCbFuncMap(),
+		wg:                   &sync.WaitGroup{},
+		ctx:                  ctx,
+		cancelCtx:            cancel,
+		guard:                newRunGuard(),
+		clock:                options.Clock,
+		o:                    options,
+		ss:                   newShutdownState(),
+		persisted:            structure.NewSet(),
+	}
+	if options.GlobalHistorySize > 0 {
+		tt.globalHistory = structure.NewQueue(options.GlobalHistorySize)
 	}
+	tt.restore()
 	tt.goExecutor()
 	// tt.goExecutorV2(maxRoutineCount)
 	tt.goTimedIssue()
 	return tt
 }
 
+// 立即关闭调度器 取消所有任务context 并等待执行线程退出
 func (tt *TimedTask) Stop() {
-	tt.shutdownIssueSign <- struct{}{}
-	for i := 0; i < int(tt.routineCount); i++ {
-		tt.shutdownExecutorSign <- struct{}{}
-	}
-	tt.wg.Wait()
-	close(tt.tasks)
-	close(tt.refreshSign)
-	return
+	tt.cancelCtx()
+	tt.Shutdown(context.Background())
 }
 
 func (tt *TimedTask) AddAddCallback(cb func(*task.AddCbArgs)) {
@@ -116,6 +197,30 @@ func (tt *TimedTask) DelUnBanCallback(cb func(*task.UnBanCbArgs)) {
 	tt.unBanCallback.Del(cb)
 }
 
+func (tt *TimedTask) AddMisfireCallback(cb func(*task.MisfireCbArgs)) {
+	tt.misfireCallback.Add(cb)
+}
+
+func (tt *TimedTask) DelMisfireCallback(cb func(*task.MisfireCbArgs)) {
+	tt.misfireCallback.Del(cb)
+}
+
+func (tt *TimedTask) AddPauseCallback(cb func(*task.PauseCbArgs)) {
+	tt.pauseCallback.Add(cb)
+}
+
+func (tt *TimedTask) DelPauseCallback(cb func(*task.PauseCbArgs)) {
+	tt.pauseCallback.Del(cb)
+}
+
+func (tt *TimedTask) AddResumeCallback(cb func(*task.ResumeCbArgs)) {
+	tt.resumeCallback.Add(cb)
+}
+
+func (tt *TimedTask) DelResumeCallback(cb func(*task.ResumeCbArgs)) {
+	tt.resumeCallback.Del(cb)
+}
+
 func (tt *TimedTask) invokeAddCallback(info *task.TaskInfo, err error) {
 	go func() {
 		addCallbacks := make([]addCallback, 0)
@@ -136,12 +241,12 @@ func (tt *TimedTask) invokeCancelCallback(key string, err error) {
 	}()
 }
 
-func (tt *TimedTask) invokeExecuteCallback(info *task.TaskInfo, res map[string]interface{}, err error, gid pool.GoroutineUID) {
+func (tt *TimedTask) invokeExecuteCallback(args *task.ExecuteCbArgs) {
 	go func() {
 		executeCallbacks := make([]executeCallback, 0)
 		tt.executeCallback.GetAll(&executeCallbacks)
 		for _, cb := range executeCallbacks {
-			cb(&task.ExecuteCbArgs{info, res, err, gid})
+			cb(args)
 		}
 	}()
 }
@@ -166,69 +271,200 @@ func (tt *TimedTask) invokeUnBanCallback(key string, err error) {
 	}()
 }
 
-func (tt *TimedTask) add(key string, obj task.TaskObj, sche task.ISchedule) error {
-	if tt.tMap.IsExist(key) {
+func (tt *TimedTask) invokeMisfireCallback(info *task.TaskInfo, dropped int, fired bool) {
+	go func() {
+		misfireCallbacks := make([]misfireCallback, 0)
+		tt.misfireCallback.GetAll(&misfireCallbacks)
+		for _, cb := range misfireCallbacks {
+			cb(&task.MisfireCbArgs{TaskInfo: info, Dropped: dropped, Fired: fired})
+		}
+	}()
+}
+
+func (tt *TimedTask) invokePauseCallback(key string, err error) {
+	go func() {
+		pauseCallbacks := make([]pauseCallback, 0)
+		tt.pauseCallback.GetAll(&pauseCallbacks)
+		for _, cb := range pauseCallbacks {
+			cb(&task.PauseCbArgs{Key: key, Error: err})
+		}
+	}()
+}
+
+func (tt *TimedTask) invokeResumeCallback(key string, err error) {
+	go func() {
+		resumeCallbacks := make([]resumeCallback, 0)
+		tt.resumeCallback.GetAll(&resumeCallbacks)
+		for _, cb := range resumeCallbacks {
+			cb(&task.ResumeCbArgs{Key: key, Error: err})
+		}
+	}()
+}
+
+func (tt *TimedTask) add(info *task.TaskInfo) error {
+	if tt.tMap.IsExist(info.Key) {
 		return ErrTaskIsExist
 	}
-	if tt.isBan(key) {
+	if tt.isBan(info.Key) {
 		return ErrTaskIsBan
 	}
-	tt.tMap.Add(key, task.NewTaskInfo(key, obj, sche))
+	tt.newKeyContext(info.Key)
+	tt.tMap.Add(info.Key, info)
 	tt.reSelectAfterUpdate()
 	return nil
 }
 
-func (tt *TimedTask) addWithCb(key string, obj task.TaskObj, sche task.ISchedule, cb bool) {
+// 返回操作后该key的任务信息副本
+func (tt *TimedTask) addWithCb(info *task.TaskInfo, cb bool) (*task.TaskInfo, error) {
+	snapshot := info.Clone()
 	tt.l.Lock()
-	err := tt.add(key, obj, sche)
+	err := tt.add(info)
+	res := tt.tMap.Get(info.Key)
 	tt.l.Unlock()
+	if err == nil {
+		tt.persistJob(info.Key)
+	}
 	if cb {
-		tt.invokeAddCallback(task.NewTaskInfo(key, obj, sche), err)
+		tt.invokeAddCallback(snapshot, err)
 	}
+	return res, err
 }
 
-func (tt *TimedTask) Add(key string, obj task.TaskObj, sche task.ISchedule) {
-	tt.addWithCb(key, obj, sche, true)
+// 添加定时任务 options为可选的任务配置
+func (tt *TimedTask) Add(key string, obj task.TaskObj, sche task.ISchedule, options ...*task.Options) {
+	tt.addWithCb(task.NewTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
 }
 
-func (tt *TimedTask) set(key string, obj task.TaskObj, sche task.ISchedule) error {
-	if tt.isBan(key) {
+// 添加支持context的定时任务 任务被取消、禁止或调度器停止时ctx会被取消
+func (tt *TimedTask) AddContext(key string, obj task.ContextTaskObj, sche task.ISchedule, options ...*task.Options) {
+	tt.addWithCb(task.NewContextTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
+}
+
+// 添加使用任务执行上下文的定时任务 任务可通过上下文获取上次执行结果、调度信息及暂存区
+func (tt *TimedTask) AddExec(key string, obj task.ExecTaskObj, sche task.ISchedule, options ...*task.Options) {
+	tt.AddContext(key, task.WrapExecTaskObj(obj), sche, options...)
+}
+
+func (tt *TimedTask) set(info *task.TaskInfo) error {
+	if tt.isBan(info.Key) {
 		return ErrTaskIsBan
 	}
-	tt.tMap.AddOrSet(key, task.NewTaskInfo(key, obj, sche))
+	if !tt.tMap.IsExist(info.Key) {
+		tt.newKeyContext(info.Key)
+	}
+	tt.tMap.AddOrSet(info.Key, info)
 	tt.reSelectAfterUpdate()
 	return nil
 }
 
-func (tt *TimedTask) setWithCb(key string, obj task.TaskObj, sche task.ISchedule, cb bool) {
+// 返回操作后该key的任务信息副本
+func (tt *TimedTask) setWithCb(info *task.TaskInfo, cb bool) (*task.TaskInfo, error) {
+	snapshot := info.Clone()
 	tt.l.Lock()
-	err := tt.set(key, obj, sche)
+	err := tt.set(info)
+	res := tt.tMap.Get(info.Key)
 	tt.l.Unlock()
+	if err == nil {
+		// 替换为未命名的任务时删除原有的持久化记录 防止重启后被恢复
+		tt.persistJob(info.Key)
+	}
 	if cb {
-		tt.invokeAddCallback(task.NewTaskInfo(key, obj, sche), err)
+		tt.invokeAddCallback(snapshot, err)
 	}
+	return res, err
 }
 
-func (tt *TimedTask) Set(key string, obj task.TaskObj, sche task.ISchedule) {
-	tt.setWithCb(key, obj, sche, true)
+// 添加或修改定时任务 options为可选的任务配置
+func (tt *TimedTask) Set(key string, obj task.TaskObj, sche task.ISchedule, options ...*task.Options) {
+	tt.setWithCb(task.NewTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
 }
 
+// 添加或修改支持context的定时任务
+func (tt *TimedTask) SetContext(key string, obj task.ContextTaskObj, sche task.ISchedule, options ...*task.Options) {
+	tt.setWithCb(task.NewContextTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
+}
+
+// 添加或修改使用任务执行上下文的定时任务
+func (tt *TimedTask) SetExec(key string, obj task.ExecTaskObj, sche task.ISchedule, options ...*task.Options) {
+	tt.SetContext(key, task.WrapExecTaskObj(obj), sche, options...)
+}
+
+// 主动取消或禁止时删除key的执行记录及执行统计 任务自然结束时保留
 func (tt *TimedTask) cancel(key string) error {
+	// 工作流调度任务已结束时仍可取消未结束的工作流执行
+	tt.cancelWorkflow(key)
+	tt.forget(key)
 	if !tt.tMap.IsExist(key) {
 		return ErrTaskIsNotExist
 	}
-	tt.tMap.Delete(key)
+	tt.remove(key)
 	tt.reSelectAfterUpdate()
 	return nil
 }
 
-func (tt *TimedTask) cancelWithCb(key string, cb bool) {
+// 清除任务 取消任务context 调用方需在锁外通过persistJob删除持久化记录
+func (tt *TimedTask) remove(key string) {
+	tt.tMap.Delete(key)
+	tt.cancelKeyContext(key)
+	tt.scratch.Delete(key)
+	if e, ok := tt.workflows.Load(key); ok {
+		tt.releaseWorkflow(e.(*workflowEntry))
+	}
+}
+
+// 删除key的执行记录及执行统计
+func (tt *TimedTask) forget(key string) {
+	tt.history.Delete(key)
+	tt.stats.Delete(key)
+}
+
+// 获取key对应的暂存区 不存在时创建
+func (tt *TimedTask) getScratch(key string) *task.Scratch {
+	if v, ok := tt.scratch.Load(key); ok {
+		return v.(*task.Scratch)
+	}
+	v, _ := tt.scratch.LoadOrStore(key, task.NewScratch())
+	return v.(*task.Scratch)
+}
+
+// 为key创建新的context 旧的context会被取消
+func (tt *TimedTask) newKeyContext(key string) {
+	ctx, cancel := context.WithCancel(tt.ctx)
+	if v, ok := tt.keyCtx.Load(key); ok {
+		v.(*keyContext).cancel()
+	}
+	tt.keyCtx.Store(key, &keyContext{ctx, cancel})
+}
+
+// 取消key对应的context 正在执行的该key任务将收到取消信号
+func (tt *TimedTask) cancelKeyContext(key string) {
+	if v, ok := tt.keyCtx.Load(key); ok {
+		v.(*keyContext).cancel()
+		tt.keyCtx.Delete(key)
+	}
+}
+
+// 获取key对应的context 不存在时返回调度器根context
+func (tt *TimedTask) getKeyContext(key string) context.Context {
+	if v, ok := tt.keyCtx.Load(key); ok {
+		return v.(*keyContext).ctx
+	}
+	return tt.ctx
+}
+
+// 返回被取消的任务信息副本
+func (tt *TimedTask) cancelWithCb(key string, cb bool) (*task.TaskInfo, error) {
 	tt.l.Lock()
+	res := tt.tMap.Get(key)
 	err := tt.cancel(key)
 	tt.l.Unlock()
+	if err == nil {
+		tt.persistJob(key)
+	}
 	if cb {
 		tt.invokeCancelCallback(key, err)
 	}
+	return res, err
 }
 
 func (tt *TimedTask) Cancel(key string) {
@@ -241,24 +477,36 @@ func (tt *TimedTask) ban(key string) error {
 	} else {
 		tt.cancel(key)
 		tt.bMap.Add(key)
+		atomic.AddInt64(&tt.counters.bans, 1)
 		return nil
 	}
 }
 
-func (tt *TimedTask) banWithCb(key string, cb bool) {
+// 返回被禁止前的任务信息副本 任务不存在时为nil
+func (tt *TimedTask) banWithCb(key string, cb bool) (*task.TaskInfo, error) {
 	tt.l.Lock()
+	res := tt.tMap.Get(key)
 	err := tt.ban(key)
 	tt.l.Unlock()
+	if err == nil {
+		tt.persistJob(key)
+		tt.persistBan(key)
+	}
 	if cb {
 		tt.invokeBanCallback(key, err)
 	}
+	if err != nil {
+		res = nil
+	}
+	return res, err
 }
 
 // 主动执行一次指定key任务 不影响既有定时任务 执行的记录将会添加到任务总结信息中
 func (tt *TimedTask) Execute(key string) {
 	ti := tt.tMap.Get(key)
 	if ti != nil {
-		tt.tasks <- ti
+		// 使用副本 防止并发执行时共用计时器
+		tt.dispatch(newTaskRun(ti.Clone()))
 	}
 }
 
@@ -275,13 +523,17 @@ func (tt *TimedTask) unBan(key string) error {
 	return nil
 }
 
-func (tt *TimedTask) unBanWithCb(key string, cb bool) {
+func (tt *TimedTask) unBanWithCb(key string, cb bool) error {
 	tt.l.Lock()
 	err := tt.unBan(key)
 	tt.l.Unlock()
+	if err == nil {
+		tt.persistBan(key)
+	}
 	if cb {
 		tt.invokeUnBanCallback(key, err)
 	}
+	return err
 }
 
 func (tt *TimedTask) UnBan(key string) {
@@ -310,33 +562,233 @@ func (tt *TimedTask) IsExist(key string) bool {
 	return b
 }
 
+// 执行一次任务并触发执行回调
+func (tt *TimedTask) execute(run *taskRun, gid pool.GoroutineUID) {
+	ti := run.ti
+	if run.step == nil && tt.tMap.Get(ti.Key) == nil {
+		tt.finish(ti.Key)
+		return
+	}
+	if run.step != nil && run.step.run.ctx.Err() != nil {
+		// 工作流已被取消 不再执行节点
+		tt.dropStep(run)
+		return
+	}
+
+	// 执行任务
+	tt.startExecuting(run, gid)
+	ti.StartTimer()
+	ctx, span := tt.startSpan(tt.runContext(run), run, gid)
+	res, returned, err := tt.runWithTimeout(ctx, ti)
+	span.End(err)
+	duration, start, end := ti.StopTimer()
+	lag := run.lag(start)
+	tt.recordHistory(run, gid, start, end, lag, res, err)
+	tt.recordStats(ti.Key, duration, lag, err)
+	tt.endExecuting(run)
+	ti.LastResult = &task.TaskResult{Result: res, Err: err}
+	if run.step == nil {
+		tt.tMap.Update(ti.Key, func(t *task.TaskInfo) {
+			t.LastResult = ti.LastResult.Clone()
+		})
+	}
+
+	// 执行失败时按重试策略稍后重试 不影响任务的调度计划
+	retry := ti.GetOptions().Retry
+	final := !retry.ShouldRetry(run.attempt, err)
+	if !final {
+		backoff := retry.NextBackoff(run.attempt)
+		afterReturned(returned, func() {
+			tt.retryLater(run, backoff)
+		})
+	}
+
+	// 如果没有下一次的执行计划 那么将会清除任务
+	if final && run.step == nil && !ti.HasNextExecute() {
+		tt.remove(ti.Key)
+		tt.persistJob(ti.Key)
+	}
+	if final {
+		// 超时的任务返回后才结束本次执行 保证并发执行策略不被打破
+		afterReturned(returned, func() {
+			tt.finish(ti.Key)
+		})
+	}
+	// 工作流节点执行结束 派发下游节点
+	if final && run.step != nil {
+		tt.stepDone(run.step, ti.LastResult)
+	}
+
+	// 执行回调
+	tt.invokeExecuteCallback(&task.ExecuteCbArgs{
+		TaskInfo: ti,
+		Res:      res,
+		Error:    err,
+		Gid:      gid,
+		Attempt:  run.attempt,
+		Final:    final,
+		Duration: duration,
+		Lag:      lag,
+	})
+}
+
+// 等待d时长后将任务重新派发给执行线程
+func (tt *TimedTask) retryLater(run *taskRun, d time.Duration) {
+	next := &taskRun{ti: run.ti.Clone(), attempt: run.attempt + 1, step: run.step, scheduled: run.scheduled, due: tt.clock.Now().Add(d), fired: run.fired}
+	if tt.isShutdown() {
+		tt.abandon(next)
+		return
+	}
+	tt.traceSchedule(next, trace.DecisionRetry, 0, d)
+	tt.ss.l.Lock()
+	defer tt.ss.l.Unlock()
+	tt.ss.retries[next] = tt.clock.AfterFunc(d, func() {
+		tt.delRetry(next)
+		select {
+		case <-tt.baseContext(next).Done():
+			if next.step != nil {
+				tt.dropStep(next)
+			} else {
+				tt.finish(next.ti.Key)
+			}
+		default:
+			tt.send(next)
+		}
+	})
+}
+
+// 按任务的并发执行策略派发一次执行
+func (tt *TimedTask) dispatch(run *taskRun) {
+	dispatch, skipped := tt.guard.acquire(run)
+	if skipped {
+		tt.traceSchedule(run, trace.DecisionSkip, 0, 0)
+		tt.invokeExecuteCallback(&task.ExecuteCbArgs{
+			TaskInfo: run.ti,
+			Error:    ErrTaskSkipped,
+			Gid:      -1,
+			Attempt:  run.attempt,
+			Final:    true,
+		})
+		if run.step != nil {
+			tt.stepDone(run.step, &task.TaskResult{Err: ErrTaskSkipped})
+		}
+		return
+	}
+	if dispatch {
+		tt.send(run)
+	}
+}
+
+// 结束一次执行 并派发排队等待的执行
+func (tt *TimedTask) finish(key string) {
+	if next := tt.guard.release(key); next != nil {
+		if tt.isShutdown() {
+			tt.abandon(next)
+			return
+		}
+		go tt.send(next)
+	}
+}
+
+// 获取一次执行所属的context 工作流节点使用所属工作流执行的context
+func (tt *TimedTask) baseContext(run *taskRun) context.Context {
+	if run.step != nil {
+		return run.step.run.ctx
+	}
+	return tt.getKeyContext(run.ti.Key)
+}
+
+// 获取一次执行使用的context 携带任务执行上下文 工作流节点的context还携带工作流执行id
+func (tt *TimedTask) runContext(run *taskRun) context.Context {
+	ctx := tt.baseContext(run)
+	ec := &task.ExecContext{
+		Key:           run.ti.Key,
+		Attempt:       run.attempt,
+		Count:         run.ti.Count,
+		ScheduledTime: run.scheduled,
+		FireTime:      run.fired,
+		PrevResult:    run.ti.LastResult.Clone(),
+		Scratch:       tt.getScratch(run.ti.Key),
+	}
+	if run.step != nil {
+		ctx = context.WithValue(ctx, workflowRunKey{}, run.step.run.id)
+		ec.Upstream = run.step.upstream()
+	}
+	return task.WithExecContext(ctx, ec)
+}
+
+// 执行任务方法 配置了超时时长时 超时后不再等待任务返回 并返回ErrTaskTimeout
+// 超时时returned在任务方法实际返回时关闭 其余情况为nil
+func (tt *TimedTask) runWithTimeout(ctx context.Context, ti *task.TaskInfo) (res map[string]interface{}, returned <-chan struct{}, err error) {
+	timeout := ti.GetOptions().Timeout
+	if timeout <= 0 {
+		res, err = runSafely(ctx, ti)
+		return res, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := tt.clock.NewTimer(timeout)
+	defer timer.Stop()
+	done := make(chan *task.TaskResult, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		res, err := runSafely(ctx, ti)
+		done <- &task.TaskResult{Result: res, Err: err}
+	}()
+	select {
+	case tr := <-done:
+		return tr.Result, nil, tr.Err
+	case <-timer.C():
+		// 超时 取消任务context 不再等待任务返回
+		return nil, exited, ErrTaskTimeout
+	}
+}
+
+// returned为nil时立即执行f 否则在returned关闭后异步执行f
+func afterReturned(returned <-chan struct{}, f func()) {
+	if returned == nil {
+		f()
+		return
+	}
+	go func() {
+		<-returned
+		f()
+	}()
+}
+
+// 执行任务方法 任务panic时恢复并转换为*pool.PanicError
+func runSafely(ctx context.Context, ti *task.TaskInfo) (res map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res, err = nil, pool.NewPanicError(r)
+		}
+	}()
+	return ti.Run(ctx)
+}
+
 func (tt *TimedTask) goExecutor() {
 	for i := 0; i < int(tt.routineCount); i++ {
+		tt.wg.Add(1)
 		go func(rid int) {
-			tt.wg.Add(1)
 			defer tt.wg.Done()
 			for {
-				var ti *task.TaskInfo
-				select {
-				case ti = <-tt.tasks:
-					break
-				case <-tt.shutdownExecutorSign:
+				if tt.isShutdown() {
 					return
 				}
-				if tt.tMap.Get(ti.Key) != nil {
-					// 执行任务
-					res, err := ti.Task()
-					ti.LastResult = &task.TaskResult{res, err}
-
-					// 如果没有下一次的执行计划 那么将会清除任务
-					if !ti.HasNextExecute() {
-						tt.tMap.Delete(ti.Key)
+				run, ok := tt.queue.pop()
+				if !ok {
+					// 队列为空 等待新任务入队
+					select {
+					case <-tt.queue.signal:
+					case <-tt.shutdownExecutorSign:
+						return
 					}
-
-					// 执行回调
-					tt.invokeExecuteCallback(ti, res, err, pool.GoroutineUID(rid))
-
+					continue
 				}
+				tt.traceDequeue(run)
+				tt.execute(run, pool.GoroutineUID(rid))
 			}
 		}(i)
 	}
@@ -349,34 +801,24 @@ func (tt *TimedTask) goExecutorV2(maxRoutineCount int) {
 
 	grd := pool.NewGoroutinePool(options)
 
+	tt.wg.Add(1)
 	go func() {
-		tt.wg.Add(1)
 		defer tt.wg.Done()
 		for {
-			var ti *task.TaskInfo
-			select {
-			case ti = <-tt.tasks:
-				break
-			case <-tt.shutdownExecutorSign:
-				grd.Stop()
-				return
+			run, ok := tt.queue.pop()
+			if !ok {
+				select {
+				case <-tt.queue.signal:
+					continue
+				case <-tt.shutdownExecutorSign:
+					grd.Shutdown(tt.ctx)
+					return
+				}
 			}
+			tt.traceDequeue(run)
 			// 构成一个任务
 			task := func(gid pool.GoroutineUID) {
-				if tt.tMap.Get(ti.Key) != nil {
-
-					// 执行任务
-					res, err := ti.Task()
-					ti.LastResult = &task.TaskResult{res, err}
-
-					// 如果没有下一次的执行计划 那么将会清除任务
-					if !ti.HasNextExecute() {
-						tt.tMap.Delete(ti.Key)
-					}
-
-					// 执行回调
-					tt.invokeExecuteCallback(ti, res, err, gid)
-				}
+				tt.execute(run, gid)
 			}
 
 			// 向动态线程池派发一个任务
@@ -386,11 +828,20 @@ func (tt *TimedTask) goExecutorV2(maxRoutineCount int) {
 }
 
 func (tt *TimedTask) goTimedIssue() {
+	tt.wg.Add(1)
 	go func() {
-		tt.wg.Add(1)
 		defer tt.wg.Done()
 		for {
-			task, spec, ok := tt.tMap.SelectNextExec()
+			if tt.isPausedAll() {
+				// 全局暂停 等待恢复后产生的刷新信号
+				select {
+				case <-tt.refreshSign:
+					continue
+				case <-tt.shutdownIssueSign:
+					return
+				}
+			}
+			task, spec, ok := tt.tMap.SelectNextExecAt(tt.clock.Now())
 			if !ok {
 				// 任务列表中没有任务 等待刷新信号来到后 重新选择任务
 				select {
@@ -401,13 +852,11 @@ func (tt *TimedTask) goTimedIssue() {
 				}
 			}
 
-			var ticker = time.NewTicker(spec)
+			var ticker = tt.clock.NewTimer(spec)
 			select {
-			case <-ticker.C:
+			case <-ticker.C():
 				ticker.Stop()
-				// 先更新任务信息再执行任务 防止调度出问题
-				tt.updateMapBeforeExec(task)
-				tt.tasks <- task
+				tt.fire(task)
 				break
 			case <-tt.refreshSign:
 				ticker.Stop()
@@ -420,24 +869,64 @@ func (tt *TimedTask) goTimedIssue() {
 	}()
 }
 
-func (tt *TimedTask) updateMapBeforeExec(task *task.TaskInfo) {
-	// 更新任务信息
-	task.Update()
-	// 写回到字典 更新
-	tt.tMap.Set(task.Key, task)
+// 到达计划时刻 按错过执行策略派发任务
+func (tt *TimedTask) fire(ti *task.TaskInfo) {
+	if tt.isPausedAll() {
+		return
+	}
+	// 先更新任务信息再执行任务 防止调度出问题
+	fire, dropped, changed := false, 0, false
+	var scheduled time.Time
+	nti, ok := tt.tMap.Update(ti.Key, func(t *task.TaskInfo) {
+		now := tt.clock.Now()
+		if t.Paused || t.NextTime.After(now) {
+			// 选出任务后任务被暂停或调度计划已改变
+			return
+		}
+		scheduled = t.NextTime
+		fire, dropped = t.Fire(now)
+		changed = true
+	})
+	if !ok {
+		return
+	}
+	if changed {
+		tt.persistJob(nti.Key)
+	}
+	run := newTaskRun(nti)
+	run.scheduled = scheduled
+	if dropped > 0 {
+		atomic.AddInt64(&tt.counters.misfires, int64(dropped))
+		tt.traceSchedule(run, trace.DecisionMisfire, dropped, 0)
+		tt.invokeMisfireCallback(nti.Clone(), dropped, fire)
+	}
+	if fire {
+		atomic.AddInt64(&tt.counters.fires, 1)
+		tt.traceSchedule(run, trace.DecisionFire, 0, 0)
+		tt.dispatch(run)
+	} else if !nti.HasNextExecute() {
+		// 跳过后没有下一次执行计划 清除任务
+		tt.remove(nti.Key)
+		tt.persistJob(nti.Key)
+	}
 }
 
 // 触发更新定时最早一个被执行的定时任务
+// 已有未处理的刷新信号时不再重复发送
 func (tt *TimedTask) reSelectAfterUpdate() {
-	if atomic.LoadInt64(&tt.singleValue) > 0 {
-		return
+	select {
+	case tt.refreshSign <- struct{}{}:
+	default:
 	}
-	atomic.AddInt64(&tt.singleValue, 1)
-	defer atomic.AddInt64(&tt.singleValue, -1)
-	tt.refreshSign <- struct{}{}
 }
 
 // 获取定时任务列表信息
 func (tt *TimedTask) GetTimedTaskInfo() map[string]*task.TaskInfo {
 	return tt.tMap.GetAll()
 }
+
+// 获取key对应的定时任务信息副本 任务不存在时返回false
+func (tt *TimedTask) GetTaskInfo(key string) (*task.TaskInfo, bool) {
+	ti := tt.tMap.Get(key)
+	return ti, ti != nil
+}
@@ -0,0 +1,71 @@
+package GoTask
+
+import "gitee.com/magicianlyx/GoTask/task"
+
+// 以下方法与对应的异步方法行为一致 回调同样会被触发
+// 区别在于直接返回操作的错误及操作后的任务信息副本
+
+// 添加定时任务 返回添加后的任务信息 key已存在时返回既有任务信息及ErrTaskIsExist
+func (tt *TimedTask) AddSync(key string, obj task.TaskObj, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.addWithCb(task.NewTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
+}
+
+// 添加支持context的定时任务
+func (tt *TimedTask) AddContextSync(key string, obj task.ContextTaskObj, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.addWithCb(task.NewContextTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
+}
+
+// 添加使用任务执行上下文的定时任务
+func (tt *TimedTask) AddExecSync(key string, obj task.ExecTaskObj, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.AddContextSync(key, task.WrapExecTaskObj(obj), sche, options...)
+}
+
+// 通过注册表中的任务方法名称添加定时任务
+func (tt *TimedTask) AddByNameSync(key string, name string, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.addByNameWithCb(key, name, sche, true, options...)
+}
+
+// 添加或修改定时任务 返回修改后的任务信息
+func (tt *TimedTask) SetSync(key string, obj task.TaskObj, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.setWithCb(task.NewTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
+}
+
+// 添加或修改支持context的定时任务
+func (tt *TimedTask) SetContextSync(key string, obj task.ContextTaskObj, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.setWithCb(task.NewContextTaskInfo(key, obj, sche).WithClock(tt.clock).WithOptions(options...), true)
+}
+
+// 添加或修改使用任务执行上下文的定时任务
+func (tt *TimedTask) SetExecSync(key string, obj task.ExecTaskObj, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.SetContextSync(key, task.WrapExecTaskObj(obj), sche, options...)
+}
+
+// 通过注册表中的任务方法名称添加或修改定时任务
+func (tt *TimedTask) SetByNameSync(key string, name string, sche task.ISchedule, options ...*task.Options) (*task.TaskInfo, error) {
+	return tt.setByNameWithCb(key, name, sche, true, options...)
+}
+
+// 取消定时任务 返回被取消的任务信息
+func (tt *TimedTask) CancelSync(key string) (*task.TaskInfo, error) {
+	return tt.cancelWithCb(key, true)
+}
+
+// 禁止key 返回被禁止时取消的任务信息 任务不存在时为nil
+func (tt *TimedTask) BanSync(key string) (*task.TaskInfo, error) {
+	return tt.banWithCb(key, true)
+}
+
+// 解除禁止key
+func (tt *TimedTask) UnBanSync(key string) error {
+	return tt.unBanWithCb(key, true)
+}
+
+// 暂停定时任务 返回暂停后的任务信息
+func (tt *TimedTask) PauseSync(key string) (*task.TaskInfo, error) {
+	return tt.pauseWithCb(key, true)
+}
+
+// 恢复被暂停的定时任务 返回恢复后的任务信息
+func (tt *TimedTask) ResumeSync(key string) (*task.TaskInfo, error) {
+	return tt.resumeWithCb(key, true)
+}
@@ -0,0 +1,867 @@
+package GoTask
+
+import (
+	"context"
+	"errors"
+	"gitee.com/magicianlyx/GoTask/clock"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/store"
+	"gitee.com/magicianlyx/GoTask/task"
+	"gitee.com/magicianlyx/GoTask/trace"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimedTask_AddContext(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, &Options{RoutineCount: 2})
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	tt.AddContext("sync", func(ctx context.Context) (map[string]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil, ctx.Err()
+	}, task.NewSpecTimeSchedule(time.Minute, 1))
+
+	fc.BlockUntil(1)
+	fc.Advance(time.Minute)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("task not started")
+	}
+	tt.Cancel("sync")
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("unexpected ctx err: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task ctx not cancelled")
+	}
+}
+
+func TestTimedTask_Timeout(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	defer close(release)
+	errs := make(chan error, 2)
+	tt.AddExecuteCallback(func(args *task.ExecuteCbArgs) {
+		errs <- args.Error
+	})
+	tt.Add("hung", func() (map[string]interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return nil, nil
+	}, task.NewSpecSchedule(time.Hour), &task.Options{Timeout: time.Second})
+
+	// 超时定时器在任务开始执行前创建 任务开始后推进时钟即可触发超时
+	for i := 0; i < 2; i++ {
+		tt.Execute("hung")
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatalf("run %d not started", i)
+		}
+		select {
+		case err := <-errs:
+			t.Fatalf("run %d finished before timeout: %v", i, err)
+		default:
+		}
+		fc.Advance(time.Second)
+		select {
+		case err := <-errs:
+			if err != ErrTaskTimeout {
+				t.Errorf("expected timeout error, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("executor still waiting on hung task")
+		}
+	}
+}
+
+// 超时后任务仍在运行时 OverlapSkip应跳过之后的调度 直到任务实际返回
+func TestTimedTask_TimeoutOverlapSkip(t *testing.T) {
+	tt := NewTimedTask(2)
+	defer tt.Stop()
+	errs := make(chan error, 100)
+	tt.AddExecuteCallback(func(args *task.ExecuteCbArgs) {
+		errs <- args.Error
+	})
+	var running, overlapped int32
+	release := make(chan struct{})
+	var calls int32
+	tt.Add("hung", func() (map[string]interface{}, error) {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		defer atomic.AddInt32(&running, -1)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// 首次执行忽略ctx 超时后继续运行
+			<-release
+		}
+		return nil, nil
+	}, task.NewSpecSchedule(10*time.Millisecond), &task.Options{Timeout: 20 * time.Millisecond, Overlap: task.OverlapSkip})
+
+	want := []error{ErrTaskTimeout, ErrTaskSkipped}
+	for len(want) > 0 {
+		select {
+		case err := <-errs:
+			if err == want[0] {
+				want = want[1:]
+			} else if err != ErrTaskSkipped {
+				t.Fatalf("unexpected error while hung task is running: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("waiting for %v", want[0])
+		}
+	}
+	close(release)
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case err := <-errs:
+			if err == nil {
+				if atomic.LoadInt32(&overlapped) == 1 {
+					t.Error("runs overlapped after timeout")
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("task not executed after hung run returned")
+		}
+	}
+}
+
+func TestTimedTask_Retry(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	args := make(chan *task.ExecuteCbArgs, 3)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		args <- a
+	})
+	fail := errors.New("fail")
+	tt.Add("flaky", func() (map[string]interface{}, error) {
+		return nil, fail
+	}, task.NewSpecTimeSchedule(time.Minute, 1), &task.Options{
+		Retry: task.NewExponentialRetryPolicy(3, time.Second, 0, 2),
+	})
+
+	// 回调前已登记重试定时器及清除任务 收到回调后推进退避时长即可触发下一次尝试
+	fc.BlockUntil(1)
+	fc.Advance(time.Minute)
+	for i := 1; i <= 3; i++ {
+		select {
+		case a := <-args:
+			if a.Attempt != i || a.Error != fail || a.Final != (i == 3) {
+				t.Errorf("attempt %d: got attempt=%d final=%v err=%v", i, a.Attempt, a.Final, a.Error)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("attempt %d not executed", i)
+		}
+		if i < 3 {
+			if !tt.IsExist("flaky") {
+				t.Errorf("attempt %d: task removed before final attempt", i)
+			}
+			fc.Advance(time.Duration(1<<uint(i-1)) * time.Second)
+		}
+	}
+	if tt.IsExist("flaky") {
+		t.Errorf("task should be removed after final attempt")
+	}
+}
+
+func TestTimedTask_Panic(t *testing.T) {
+	tt := NewTimedTask(1)
+	errs := make(chan error, 1)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		errs <- a.Error
+	})
+	tt.Add("panic", func() (map[string]interface{}, error) {
+		panic("boom")
+	}, task.NewSpecTimeSchedule(10*time.Millisecond, 1))
+
+	select {
+	case err := <-errs:
+		if pe, ok := err.(*pool.PanicError); !ok || pe.Value != "boom" {
+			t.Errorf("expected panic error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("panic not reported")
+	}
+}
+
+func TestTimedTask_Overlap(t *testing.T) {
+	// 上次执行未结束时再触发两次 OverlapSkip跳过两次 OverlapQueue排队一次跳过一次
+	cases := []struct {
+		policy  task.OverlapPolicy
+		skipped int
+		runs    int
+	}{
+		{task.OverlapSkip, 2, 1},
+		{task.OverlapQueue, 1, 2},
+	}
+	for _, c := range cases {
+		func() {
+			fc, tt := newFakeClockTimedTask(t, &Options{RoutineCount: 4})
+			skipped := make(chan struct{}, 3)
+			tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+				if a.Error == ErrTaskSkipped {
+					skipped <- struct{}{}
+				}
+			})
+			var running, peak int32
+			started, release := make(chan struct{}, 3), make(chan struct{})
+			tt.Add("slow", func() (map[string]interface{}, error) {
+				if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&peak) {
+					atomic.StoreInt32(&peak, n)
+				}
+				defer atomic.AddInt32(&running, -1)
+				started <- struct{}{}
+				<-release
+				return nil, nil
+			}, task.NewSpecSchedule(time.Minute), &task.Options{Overlap: c.policy})
+
+			fc.BlockUntil(1)
+			fc.Advance(time.Minute)
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatalf("%s: task not started", c.policy.ToString())
+			}
+			for i := 0; i < 2; i++ {
+				fc.BlockUntil(1)
+				fc.Advance(time.Minute)
+			}
+			for i := 0; i < c.skipped; i++ {
+				select {
+				case <-skipped:
+				case <-time.After(time.Second):
+					t.Fatalf("%s: expected %d skipped fires, got %d", c.policy.ToString(), c.skipped, i)
+				}
+			}
+			close(release)
+			for i := 1; i < c.runs; i++ {
+				select {
+				case <-started:
+				case <-time.After(time.Second):
+					t.Fatalf("%s: queued run not executed", c.policy.ToString())
+				}
+			}
+
+			tt.Cancel("slow")
+			tt.Stop()
+			if len(started) != 0 || len(skipped) != 0 {
+				t.Errorf("%s: unexpected extra runs %d or skips %d", c.policy.ToString(), len(started), len(skipped))
+			}
+			if p := atomic.LoadInt32(&peak); p != 1 {
+				t.Errorf("%s: expected no overlap, peak %d", c.policy.ToString(), p)
+			}
+		}()
+	}
+}
+
+// 伪造时钟的起始时刻
+var fakeClockStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+
+// 创建使用伪造时钟的调度器 o为nil时使用单个执行线程 测试结束时关闭调度器
+func newFakeClockTimedTask(t *testing.T, o *Options) (*clock.FakeClock, *TimedTask) {
+	if o == nil {
+		o = &Options{RoutineCount: 1}
+	}
+	fc := clock.NewFakeClock(fakeClockStart)
+	o.Clock = fc
+	tt := NewTimedTaskWithOptions(o)
+	t.Cleanup(tt.Stop)
+	return fc, tt
+}
+
+func TestTimedTask_FakeClock(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	ran := make(chan time.Time, 1)
+	tt.Add("daily", func() (map[string]interface{}, error) {
+		ran <- fc.Now()
+		return nil, nil
+	}, task.NewEveryDaySchedule(3, 0, 0, 0))
+
+	for i := 0; i < 3; i++ {
+		fc.BlockUntil(1)
+		fc.Set(time.Date(2020, 1, 1+i, 2, 59, 59, 0, time.Local))
+		fc.BlockUntil(1)
+		select {
+		case <-ran:
+			t.Fatalf("run %d fired too early", i)
+		default:
+		}
+		due := time.Date(2020, 1, 1+i, 3, 0, 0, 0, time.Local)
+		fc.Set(due)
+		select {
+		case now := <-ran:
+			if !now.Equal(due) {
+				t.Errorf("run %d at %v, want %v", i, now, due)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("run %d not fired", i)
+		}
+	}
+}
+
+func TestTimedTask_Misfire(t *testing.T) {
+	cases := []struct {
+		name      string
+		o         *task.Options
+		jump      time.Duration
+		runs      int
+		misfire   bool
+		dropped   int
+		fired     bool
+		misfired  int
+		remaining int // 跳转后下次计划时刻距start的分钟数
+	}{
+		// 补执行所有错过的调度
+		{name: "fire_all", o: &task.Options{Misfire: task.MisfireFireAll}, jump: 5*time.Minute + 30*time.Second, runs: 5, remaining: 6},
+		// 执行一次 丢弃其余4次
+		{name: "fire_once", o: &task.Options{Misfire: task.MisfireFireOnce}, jump: 5*time.Minute + 30*time.Second, runs: 1, misfire: true, dropped: 4, fired: true, misfired: 4, remaining: 6},
+		// 丢弃全部5次
+		{name: "skip", o: &task.Options{Misfire: task.MisfireSkip}, jump: 5*time.Minute + 30*time.Second, misfire: true, dropped: 5, misfired: 5, remaining: 6},
+		// 延迟未超过阈值 正常执行
+		{name: "threshold", o: &task.Options{Misfire: task.MisfireSkip, MisfireThreshold: time.Minute}, jump: time.Minute + 30*time.Second, runs: 1, remaining: 2},
+	}
+	for _, c := range cases {
+		fc, tt := newFakeClockTimedTask(t, nil)
+		ran := make(chan struct{}, 10)
+		misfires := make(chan *task.MisfireCbArgs, 1)
+		tt.AddMisfireCallback(func(a *task.MisfireCbArgs) {
+			misfires <- a
+		})
+		tt.Add("tick", func() (map[string]interface{}, error) {
+			ran <- struct{}{}
+			return nil, nil
+		}, task.NewSpecSchedule(time.Minute), c.o)
+
+		fc.BlockUntil(1)
+		fc.Set(fakeClockStart.Add(c.jump))
+		for i := 0; i < c.runs; i++ {
+			select {
+			case <-ran:
+			case <-time.After(time.Second):
+				t.Fatalf("%s: run %d not fired", c.name, i+1)
+			}
+		}
+		if c.misfire {
+			select {
+			case a := <-misfires:
+				if a.Dropped != c.dropped || a.Fired != c.fired {
+					t.Errorf("%s: dropped %d fired %v, want %d %v", c.name, a.Dropped, a.Fired, c.dropped, c.fired)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s: misfire callback not invoked", c.name)
+			}
+		}
+		// 等待调度器对齐到下一个计划时刻
+		fc.BlockUntil(1)
+		select {
+		case <-ran:
+			t.Errorf("%s: unexpected extra run", c.name)
+		case a := <-misfires:
+			t.Errorf("%s: unexpected misfire callback %+v", c.name, a)
+		case <-time.After(20 * time.Millisecond):
+		}
+		ti, ok := tt.GetTaskInfo("tick")
+		if !ok || ti.Misfired != c.misfired || ti.NextTime != fakeClockStart.Add(time.Duration(c.remaining)*time.Minute) {
+			t.Errorf("%s: unexpected task info %+v", c.name, ti)
+		}
+		tt.Stop()
+	}
+}
+
+func TestTimedTask_Restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotask-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry := task.NewRegistry()
+	ran := make(chan struct{}, 10)
+	registry.Register("report", func() (map[string]interface{}, error) {
+		ran <- struct{}{}
+		return nil, nil
+	})
+
+	s, err := store.NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := NewTimedTaskWithOptions(&Options{RoutineCount: 1, Store: s, Registry: registry})
+	tt.AddByName("report", "report", task.NewSpecSchedule(20*time.Millisecond))
+	tt.Ban("other")
+	<-ran
+	<-ran
+	tt.Stop()
+	s.Close()
+	count := tt.GetTimedTaskInfo()["report"].Count
+
+	s, err = store.NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	tt = NewTimedTaskWithOptions(&Options{RoutineCount: 1, Store: s, Registry: registry})
+	info, ok := tt.GetTimedTaskInfo()["report"]
+	if !ok || info.Count < count {
+		t.Fatalf("task not restored: %+v", info)
+	}
+	if !tt.IsBan("other") {
+		t.Errorf("ban list not restored")
+	}
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("restored task not scheduled")
+	}
+}
+
+// 未配置注册表时仍恢复禁止列表 任务通过存储错误通知
+func TestTimedTask_RestoreWithoutRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotask-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry := task.NewRegistry()
+	registry.Register("report", func() (map[string]interface{}, error) {
+		return nil, nil
+	})
+	s, err := store.NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := NewTimedTaskWithOptions(&Options{RoutineCount: 1, Store: s, Registry: registry})
+	if _, err := tt.AddByNameSync("report", "report", task.NewSpecSchedule(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	tt.Ban("other")
+	tt.Stop()
+	s.Close()
+
+	s, err = store.NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var errs []error
+	tt = NewTimedTaskWithOptions(&Options{RoutineCount: 1, Store: s, StoreErrorHandler: func(err error) {
+		errs = append(errs, err)
+	}})
+	defer tt.Stop()
+	if !tt.IsBan("other") {
+		t.Errorf("ban list not restored")
+	}
+	if tt.IsExist("report") || len(errs) != 1 || !errors.Is(errs[0], task.ErrTaskNameNotRegistered) {
+		t.Errorf("unexpected restore result: %v", errs)
+	}
+}
+
+// 以未命名的任务替换已持久化的任务时 删除原有的持久化记录
+func TestTimedTask_SetUnnamed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotask-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry := task.NewRegistry()
+	registry.Register("report", func() (map[string]interface{}, error) {
+		return nil, nil
+	})
+	s, err := store.NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	tt := NewTimedTaskWithOptions(&Options{RoutineCount: 1, Store: s, Registry: registry})
+	defer tt.Stop()
+	if _, err := tt.AddByNameSync("report", "report", task.NewSpecSchedule(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _, _ := s.Load(); len(jobs) != 1 {
+		t.Fatalf("named task not persisted")
+	}
+	if _, err := tt.SetSync("report", func() (map[string]interface{}, error) {
+		return nil, nil
+	}, task.NewSpecSchedule(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _, _ := s.Load(); len(jobs) != 0 {
+		t.Errorf("record of replaced task should be deleted, got %d", len(jobs))
+	}
+}
+
+func TestTimedTask_Sync(t *testing.T) {
+	tt := NewTimedTask(1)
+	obj := func() (map[string]interface{}, error) {
+		return nil, nil
+	}
+	sche := task.NewSpecSchedule(time.Hour)
+
+	ti, err := tt.AddSync("a", obj, sche)
+	if err != nil || ti == nil || ti.Key != "a" {
+		t.Fatalf("add: %v %v", ti, err)
+	}
+	if ti, err = tt.AddSync("a", obj, sche); err != ErrTaskIsExist || ti == nil {
+		t.Errorf("duplicate add: %v %v", ti, err)
+	}
+	if ti, err = tt.CancelSync("a"); err != nil || ti == nil || ti.Key != "a" {
+		t.Errorf("cancel: %v %v", ti, err)
+	}
+	if _, err = tt.CancelSync("a"); err != ErrTaskIsNotExist {
+		t.Errorf("cancel missing: %v", err)
+	}
+	if _, err = tt.BanSync("a"); err != nil {
+		t.Errorf("ban: %v", err)
+	}
+	if ti, err = tt.SetSync("a", obj, sche); err != ErrTaskIsBan || ti != nil {
+		t.Errorf("set banned: %v %v", ti, err)
+	}
+	if err = tt.UnBanSync("a"); err != nil {
+		t.Errorf("unban: %v", err)
+	}
+	if err = tt.UnBanSync("a"); err != ErrTaskIsUnBan {
+		t.Errorf("unban twice: %v", err)
+	}
+	if _, err = tt.AddByNameSync("b", "missing", sche); err != task.ErrTaskNameNotRegistered {
+		t.Errorf("add by missing name: %v", err)
+	}
+}
+
+func TestTimedTask_Shutdown(t *testing.T) {
+	tt := NewTimedTask(1)
+	started := make(chan struct{}, 1)
+	var done int32
+	tt.Add("slow", func() (map[string]interface{}, error) {
+		started <- struct{}{}
+		time.Sleep(30 * time.Millisecond)
+		atomic.AddInt32(&done, 1)
+		return nil, nil
+	}, task.NewSpecSchedule(10*time.Millisecond))
+	<-started
+
+	report, err := tt.Shutdown(context.Background())
+	if err != nil || len(report.Abandoned) != 0 {
+		t.Fatalf("unexpected shutdown result: %+v %v", report, err)
+	}
+	if atomic.LoadInt32(&done) == 0 {
+		t.Errorf("in-flight run should finish before shutdown returns")
+	}
+	n := atomic.LoadInt32(&done)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&done) != n {
+		t.Errorf("task fired after shutdown")
+	}
+	tt.Execute("slow")
+	tt.Stop()
+}
+
+func TestTimedTask_ShutdownDeadline(t *testing.T) {
+	tt := NewTimedTask(1)
+	started := make(chan struct{})
+	tt.AddContext("hung", func(ctx context.Context) (map[string]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, task.NewSpecTimeSchedule(10*time.Millisecond, 1))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := tt.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if len(report.Abandoned) != 1 || report.Abandoned[0].Key != "hung" || !report.Abandoned[0].Started {
+		t.Errorf("unexpected report: %+v", report.Abandoned)
+	}
+	if _, err = tt.Shutdown(context.Background()); err != nil {
+		t.Errorf("second shutdown: %v", err)
+	}
+}
+
+func TestTimedTask_Pause(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	ran := make(chan struct{}, 10)
+	tt.Add("tick", func() (map[string]interface{}, error) {
+		ran <- struct{}{}
+		return nil, nil
+	}, task.NewSpecSchedule(time.Minute))
+
+	ti, err := tt.PauseSync("tick")
+	if err != nil || !ti.Paused || !tt.IsPaused("tick") {
+		t.Fatalf("pause: %v %v", ti, err)
+	}
+	if _, err = tt.PauseSync("tick"); err != ErrTaskIsPaused {
+		t.Errorf("pause twice: %v", err)
+	}
+	if !tt.GetTimedTaskInfo()["tick"].Paused {
+		t.Errorf("paused flag not exposed")
+	}
+	if ti, ok := tt.GetTaskInfo("tick"); !ok || !ti.Paused {
+		t.Errorf("single task info: %v %v", ti, ok)
+	}
+	if _, ok := tt.GetTaskInfo("missing"); ok {
+		t.Errorf("missing key should not exist")
+	}
+	fc.Set(fakeClockStart.Add(5*time.Minute + time.Second))
+	select {
+	case <-ran:
+		t.Fatal("paused task fired")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ti, err = tt.ResumeSync("tick")
+	if err != nil || ti.Paused || ti.AddTime != fakeClockStart || ti.Count != 5 || ti.NextTime != fakeClockStart.Add(6*time.Minute) {
+		t.Fatalf("resume: %+v %v", ti, err)
+	}
+	if _, err = tt.ResumeSync("tick"); err != ErrTaskIsUnPaused {
+		t.Errorf("resume twice: %v", err)
+	}
+	fc.BlockUntil(1)
+	fc.Set(fakeClockStart.Add(6 * time.Minute))
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("resumed task not fired")
+	}
+}
+
+func TestTimedTask_PauseAll(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	ran := make(chan struct{}, 10)
+	tt.Add("tick", func() (map[string]interface{}, error) {
+		ran <- struct{}{}
+		return nil, nil
+	}, task.NewSpecSchedule(time.Minute))
+
+	fc.BlockUntil(1)
+	tt.PauseAll()
+	if at, paused := tt.IsPausedAll(); !paused || !at.Equal(fakeClockStart) {
+		t.Fatalf("scheduler should be paused at %v, got %v %v", fakeClockStart, at, paused)
+	}
+	fc.Set(fakeClockStart.Add(5*time.Minute + time.Second))
+	select {
+	case <-ran:
+		t.Fatal("task fired while scheduler paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// 执行线程不受全局暂停影响
+	tt.Execute("tick")
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("executor should keep running while paused")
+	}
+
+	tt.ResumeAll()
+	if _, paused := tt.IsPausedAll(); paused {
+		t.Fatal("scheduler should be resumed")
+	}
+	if ti := tt.GetTimedTaskInfo()["tick"]; ti.Count != 5 || ti.NextTime != fakeClockStart.Add(6*time.Minute) {
+		t.Errorf("missed fires should be skipped: %+v", ti)
+	}
+	fc.BlockUntil(1)
+	fc.Set(fakeClockStart.Add(6 * time.Minute))
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("task not fired after resume")
+	}
+}
+
+func TestTimedTask_AddExec(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, nil)
+	ecs := make(chan *task.ExecContext, 1)
+	tt.AddExec("sync", func(ec *task.ExecContext) (map[string]interface{}, error) {
+		mark := ec.Scratch.Update("mark", func(old interface{}, ok bool) interface{} {
+			if !ok {
+				return 1
+			}
+			return old.(int) + 1
+		})
+		ecs <- ec
+		return map[string]interface{}{"mark": mark}, nil
+	}, task.NewSpecSchedule(time.Minute))
+
+	for i := 1; i <= 2; i++ {
+		fc.BlockUntil(1)
+		fc.Set(fakeClockStart.Add(time.Duration(i)*time.Minute + time.Millisecond))
+		var ec *task.ExecContext
+		select {
+		case ec = <-ecs:
+		case <-time.After(time.Second):
+			t.Fatalf("run %d not fired", i)
+		}
+		if ec.Count != i || ec.ScheduledTime != fakeClockStart.Add(time.Duration(i)*time.Minute) || ec.Lag() != time.Millisecond {
+			t.Errorf("run %d: count %d scheduled %v lag %v", i, ec.Count, ec.ScheduledTime, ec.Lag())
+		}
+		if v, _ := ec.Scratch.Get("mark"); v != i {
+			t.Errorf("run %d: scratch mark %v", i, v)
+		}
+		if i == 1 && ec.PrevResult != nil {
+			t.Errorf("first run should have no previous result")
+		}
+		if i == 2 && (ec.PrevResult == nil || ec.PrevResult.Result["mark"] != 1) {
+			t.Errorf("second run should see previous result, got %+v", ec.PrevResult)
+		}
+	}
+}
+
+func TestTimedTask_GetHistory(t *testing.T) {
+	fc, tt := newFakeClockTimedTask(t, &Options{RoutineCount: 1, HistorySize: 2, GlobalHistorySize: 10})
+	done := make(chan struct{}, 3)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		done <- struct{}{}
+	})
+	fail := errors.New("fail")
+	tt.Add("job", func() (map[string]interface{}, error) {
+		return map[string]interface{}{"ok": true}, fail
+	}, task.NewSpecSchedule(time.Minute))
+	for i := 0; i < 3; i++ {
+		fc.BlockUntil(1)
+		fc.Set(fakeClockStart.Add(time.Duration(i+1) * time.Minute))
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("run %d not executed", i)
+		}
+	}
+
+	history := tt.GetHistory("job", 0)
+	if len(history) != 2 {
+		t.Fatalf("history should keep 2 entries, got %d", len(history))
+	}
+	if !history[0].StartTime.After(history[1].StartTime) {
+		t.Errorf("history should be newest first")
+	}
+	h := history[0]
+	if h.Error != fail || h.Result["ok"] != true || h.Attempt != 1 || h.ScheduledTime.IsZero() || h.EndTime.Before(h.StartTime) {
+		t.Errorf("unexpected entry: %+v", h)
+	}
+	if len(tt.GetHistory("job", 1)) != 1 || len(tt.GetHistory("missing", 1)) != 0 {
+		t.Errorf("unexpected history limit")
+	}
+	if len(tt.GetGlobalHistory(0)) != 3 {
+		t.Errorf("global history should keep all 3 entries")
+	}
+
+	// 任务被取消后记录一并删除
+	tt.Cancel("job")
+	if len(tt.GetHistory("job", 0)) != 0 || len(tt.GetGlobalHistory(0)) != 3 {
+		t.Errorf("history should be removed with the task")
+	}
+}
+
+func TestTimedTask_GetHistoryAfterFinish(t *testing.T) {
+	tt := NewTimedTask(1)
+	defer tt.Stop()
+	done := make(chan struct{}, 1)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		done <- struct{}{}
+	})
+	tt.Add("once", func() (map[string]interface{}, error) {
+		return map[string]interface{}{"ok": true}, nil
+	}, task.NewSpecTimeSchedule(10*time.Millisecond, 1))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task not executed")
+	}
+
+	// 任务执行结束后被清除 执行记录及统计仍保留
+	if tt.IsExist("once") {
+		t.Fatal("finished task should be removed")
+	}
+	history := tt.GetHistory("once", 0)
+	if len(history) != 1 || history[0].Result["ok"] != true {
+		t.Errorf("history should be kept after the task finished: %+v", history)
+	}
+	if s, ok := tt.GetRunStats("once"); !ok || s.Count != 1 {
+		t.Errorf("stats should be kept after the task finished: %+v", s)
+	}
+	tt.ClearHistory("once")
+	if _, ok := tt.GetRunStats("once"); ok || len(tt.GetHistory("once", 0)) != 0 {
+		t.Errorf("history and stats should be cleared")
+	}
+}
+
+func TestTimedTask_Tracer(t *testing.T) {
+	r := trace.NewRecorder(nil)
+	tt := NewTimedTaskWithOptions(&Options{RoutineCount: 1, Tracer: r})
+	defer tt.Stop()
+	done := make(chan struct{}, 1)
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		if a.Final {
+			done <- struct{}{}
+		}
+	})
+	fail := errors.New("fail")
+	tt.AddContext("job", func(ctx context.Context) (map[string]interface{}, error) {
+		if _, ok := trace.SpanFromContext(ctx); !ok {
+			t.Errorf("span should be propagated to task context")
+		}
+		return nil, fail
+	}, task.NewSpecTimeSchedule(10*time.Millisecond, 1), &task.Options{Retry: &task.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task not executed")
+	}
+
+	spans := r.Spans()
+	if len(spans) != 2 || spans[0].Info.Key != "job" || spans[1].Info.Attempt != 2 || spans[1].Error != fail {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+	decisions := make([]trace.Decision, 0)
+	for _, e := range r.Scheduled() {
+		decisions = append(decisions, e.Decision)
+	}
+	if len(decisions) != 2 || decisions[0] != trace.DecisionFire || decisions[1] != trace.DecisionRetry {
+		t.Errorf("unexpected decisions: %v", decisions)
+	}
+	if len(r.Enqueued()) != 2 || len(r.Dequeued()) != 2 {
+		t.Errorf("unexpected queue events")
+	}
+
+	// 工作流节点的跨度以触发工作流的跨度为上游
+	r.Reset()
+	finished := make(chan struct{}, 1)
+	tt.AddWorkflowCallback(func(args *WorkflowCbArgs) {
+		finished <- struct{}{}
+	})
+	nop := func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, nil
+	}
+	w := NewWorkflow("flow")
+	w.Node("a", nop)
+	w.Node("b", nop, "a")
+	if err := tt.AddWorkflow(w, task.NewSpecTimeSchedule(10*time.Millisecond, 1)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("workflow not finished")
+	}
+	spans = r.Spans()
+	if len(spans) != 3 || spans[0].Info.Key != "flow" {
+		t.Fatalf("unexpected workflow spans: %+v", spans)
+	}
+	for _, s := range spans[1:] {
+		if s.ParentID != spans[0].ID {
+			t.Errorf("%s should link to trigger span", s.Info.Key)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package trace
+
+import "context"
+
+// 不做任何记录的埋点实现
+type NoopTracer struct{}
+
+func NewNoopTracer() *NoopTracer {
+	return &NoopTracer{}
+}
+
+func (t *NoopTracer) StartRun(ctx context.Context, info *RunInfo) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (t *NoopTracer) Enqueue(e *QueueEvent) {}
+
+func (t *NoopTracer) Dequeue(e *QueueEvent) {}
+
+func (t *NoopTracer) Schedule(e *ScheduleEvent) {}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) End(err error) {}
+
+// 为空时返回不做任何记录的埋点实现
+func OrNoop(t Tracer) Tracer {
+	if t == nil {
+		return NewNoopTracer()
+	}
+	return t
+}
@@ -0,0 +1,157 @@
+package trace
+
+import (
+	"context"
+	"gitee.com/magicianlyx/GoTask/clock"
+	"sync"
+	"time"
+)
+
+// 被记录的跨度
+type RecordedSpan struct {
+	ID         uint64
+	ParentID   uint64 // 开始时context中已有的记录跨度id 没有时为0
+	Info       RunInfo
+	Attributes map[string]interface{}
+	Error      error
+	Ended      bool
+	EndTime    time.Time
+}
+
+// 将所有埋点记录在内存中的实现 用于测试
+type Recorder struct {
+	l         sync.Mutex
+	c         clock.Clock
+	seq       uint64
+	spans     []*RecordedSpan
+	enqueues  []*QueueEvent
+	dequeues  []*QueueEvent
+	schedules []*ScheduleEvent
+}
+
+// c为记录跨度结束时刻使用的时钟 为空时使用系统时钟
+func NewRecorder(c clock.Clock) *Recorder {
+	r := &Recorder{l: sync.Mutex{}, c: clock.OrDefault(c)}
+	r.Reset()
+	return r
+}
+
+func (r *Recorder) StartRun(ctx context.Context, info *RunInfo) (context.Context, Span) {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.seq++
+	rs := &RecordedSpan{ID: r.seq, Info: *info, Attributes: make(map[string]interface{})}
+	if parent, ok := SpanFromContext(ctx); ok {
+		if p, ok := parent.(*recorderSpan); ok {
+			rs.ParentID = p.s.ID
+		}
+	}
+	r.spans = append(r.spans, rs)
+	span := &recorderSpan{r: r, s: rs}
+	return ContextWithSpan(ctx, span), span
+}
+
+func (r *Recorder) Enqueue(e *QueueEvent) {
+	ce := *e
+	r.l.Lock()
+	r.enqueues = append(r.enqueues, &ce)
+	r.l.Unlock()
+}
+
+func (r *Recorder) Dequeue(e *QueueEvent) {
+	ce := *e
+	r.l.Lock()
+	r.dequeues = append(r.dequeues, &ce)
+	r.l.Unlock()
+}
+
+func (r *Recorder) Schedule(e *ScheduleEvent) {
+	ce := *e
+	r.l.Lock()
+	r.schedules = append(r.schedules, &ce)
+	r.l.Unlock()
+}
+
+// 获取所有跨度副本 按开始顺序排列
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.l.Lock()
+	defer r.l.Unlock()
+	res := make([]*RecordedSpan, 0, len(r.spans))
+	for _, s := range r.spans {
+		cs := *s
+		cs.Attributes = make(map[string]interface{})
+		for k, v := range s.Attributes {
+			cs.Attributes[k] = v
+		}
+		res = append(res, &cs)
+	}
+	return res
+}
+
+// 获取所有入队事件副本
+func (r *Recorder) Enqueued() []*QueueEvent {
+	r.l.Lock()
+	defer r.l.Unlock()
+	return cloneQueueEvents(r.enqueues)
+}
+
+// 获取所有出队事件副本
+func (r *Recorder) Dequeued() []*QueueEvent {
+	r.l.Lock()
+	defer r.l.Unlock()
+	return cloneQueueEvents(r.dequeues)
+}
+
+// 获取所有调度决策副本
+func (r *Recorder) Scheduled() []*ScheduleEvent {
+	r.l.Lock()
+	defer r.l.Unlock()
+	res := make([]*ScheduleEvent, 0, len(r.schedules))
+	for _, e := range r.schedules {
+		ce := *e
+		res = append(res, &ce)
+	}
+	return res
+}
+
+// 清空所有记录
+func (r *Recorder) Reset() {
+	r.l.Lock()
+	defer r.l.Unlock()
+	r.spans = make([]*RecordedSpan, 0)
+	r.enqueues = make([]*QueueEvent, 0)
+	r.dequeues = make([]*QueueEvent, 0)
+	r.schedules = make([]*ScheduleEvent, 0)
+}
+
+func cloneQueueEvents(events []*QueueEvent) []*QueueEvent {
+	res := make([]*QueueEvent, 0, len(events))
+	for _, e := range events {
+		ce := *e
+		res = append(res, &ce)
+	}
+	return res
+}
+
+type recorderSpan struct {
+	r *Recorder
+	s *RecordedSpan
+}
+
+func (s *recorderSpan) SetAttribute(key string, value interface{}) {
+	s.r.l.Lock()
+	defer s.r.l.Unlock()
+	s.s.Attributes[key] = value
+}
+
+// 只有第一次结束生效
+func (s *recorderSpan) End(err error) {
+	s.r.l.Lock()
+	defer s.r.l.Unlock()
+	if s.s.Ended {
+		return
+	}
+	s.s.Ended = true
+	s.s.Error = err
+	s.s.EndTime = s.r.c.Now()
+}
@@ -0,0 +1,34 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecorder_StartRun(t *testing.T) {
+	r := NewRecorder(nil)
+	ctx, parent := r.StartRun(context.Background(), &RunInfo{Key: "parent"})
+	_, child := r.StartRun(ctx, &RunInfo{Key: "child"})
+	child.SetAttribute("rows", 3)
+	fail := errors.New("fail")
+	child.End(fail)
+	child.End(nil)
+	parent.End(nil)
+
+	spans := r.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	if spans[0].ParentID != 0 || spans[1].ParentID != spans[0].ID {
+		t.Errorf("child should link to parent: %+v %+v", spans[0], spans[1])
+	}
+	if !spans[1].Ended || spans[1].Error != fail || spans[1].Attributes["rows"] != 3 {
+		t.Errorf("unexpected child span: %+v", spans[1])
+	}
+
+	r.Reset()
+	if len(r.Spans()) != 0 {
+		t.Errorf("reset should clear spans")
+	}
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"context"
+	"time"
+)
+
+// 调用方组件
+const (
+	ComponentScheduler = "scheduler" // 定时任务组件
+	ComponentPool      = "pool"      // 线程池
+)
+
+// 一次任务执行的信息
+type RunInfo struct {
+	Component     string
+	Key           string    // 任务key 线程池任务为空
+	Gid           int64     // 执行线程id
+	Attempt       int       // 第几次尝试 线程池任务为0
+	Priority      int       // 优先级
+	ScheduledTime time.Time // 计划时刻 主动执行及线程池任务为time.Time{}
+	StartTime     time.Time // 开始执行时刻
+}
+
+// 一次排队 入队时DequeueTime为time.Time{}
+type QueueEvent struct {
+	Component   string
+	Key         string // 任务key 线程池任务为空
+	Priority    int
+	EnqueueTime time.Time
+	DequeueTime time.Time
+}
+
+// 排队等待时长 未出队时为0
+func (e *QueueEvent) Wait() time.Duration {
+	if e.DequeueTime.IsZero() {
+		return 0
+	}
+	return e.DequeueTime.Sub(e.EnqueueTime)
+}
+
+// 调度决策
+type Decision int
+
+const (
+	DecisionFire    Decision = 0 // 按调度计划触发执行
+	DecisionMisfire Decision = 1 // 错过的调度被丢弃
+	DecisionSkip    Decision = 2 // 上一次执行仍未结束 本次执行被跳过
+	DecisionRetry   Decision = 3 // 执行失败 稍后重试
+	DecisionAbandon Decision = 4 // 调度器关闭 执行被丢弃
+)
+
+func (d Decision) ToString() string {
+	switch d {
+	case DecisionMisfire:
+		return "misfire"
+	case DecisionSkip:
+		return "skip"
+	case DecisionRetry:
+		return "retry"
+	case DecisionAbandon:
+		return "abandon"
+	default:
+		return "fire"
+	}
+}
+
+func (d Decision) IsValid() bool {
+	return d >= DecisionFire && d <= DecisionAbandon
+}
+
+// 一次调度决策
+type ScheduleEvent struct {
+	Key           string
+	Decision      Decision
+	Time          time.Time     // 决策时刻
+	ScheduledTime time.Time     // 计划时刻 主动执行时为time.Time{}
+	Attempt       int           // 涉及的尝试次数 重试时为下一次尝试
+	Dropped       int           // 被丢弃的调度次数 仅DecisionMisfire时有效
+	Delay         time.Duration // 重试等待时长 仅DecisionRetry时有效
+}
+
+// 一次任务执行的跨度
+type Span interface {
+	// 设置属性
+	SetAttribute(key string, value interface{})
+	// 结束跨度 err为执行结果的错误
+	End(err error)
+}
+
+// 任务执行的埋点接口 实现需线程安全 且不应阻塞调用方
+type Tracer interface {
+	// 任务执行开始 返回的context传递给任务方法 返回的跨度在执行结束时结束
+	StartRun(ctx context.Context, info *RunInfo) (context.Context, Span)
+	// 任务入队
+	Enqueue(e *QueueEvent)
+	// 任务出队
+	Dequeue(e *QueueEvent)
+	// 调度决策
+	Schedule(e *ScheduleEvent)
+}
+
+type spanKey struct{}
+
+// 将跨度放入context 用于向下游传递
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// 获取context中的跨度
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	return span, ok
+}
@@ -0,0 +1,58 @@
+package GoTask
+
+import (
+	"context"
+	"gitee.com/magicianlyx/GoTask/pool"
+	"gitee.com/magicianlyx/GoTask/trace"
+	"time"
+)
+
+// 记录一次调度决策
+func (tt *TimedTask) traceSchedule(run *taskRun, decision trace.Decision, dropped int, delay time.Duration) {
+	tt.o.Tracer.Schedule(&trace.ScheduleEvent{
+		Key:           run.ti.Key,
+		Decision:      decision,
+		Time:          tt.clock.Now(),
+		ScheduledTime: run.scheduled,
+		Attempt:       run.attempt,
+		Dropped:       dropped,
+		Delay:         delay,
+	})
+}
+
+// 记录一次入队
+func (tt *TimedTask) traceEnqueue(run *taskRun) {
+	tt.o.Tracer.Enqueue(&trace.QueueEvent{
+		Component:   trace.ComponentScheduler,
+		Key:         run.ti.Key,
+		Priority:    int(run.ti.GetOptions().Priority),
+		EnqueueTime: run.queued,
+	})
+}
+
+// 记录一次出队
+func (tt *TimedTask) traceDequeue(run *taskRun) {
+	tt.o.Tracer.Dequeue(&trace.QueueEvent{
+		Component:   trace.ComponentScheduler,
+		Key:         run.ti.Key,
+		Priority:    int(run.ti.GetOptions().Priority),
+		EnqueueTime: run.queued,
+		DequeueTime: tt.clock.Now(),
+	})
+}
+
+// 开始一次执行的跨度 工作流节点以触发工作流的执行跨度为上游
+func (tt *TimedTask) startSpan(ctx context.Context, run *taskRun, gid pool.GoroutineUID) (context.Context, trace.Span) {
+	if run.step != nil && run.step.run.span != nil {
+		ctx = trace.ContextWithSpan(ctx, run.step.run.span)
+	}
+	return tt.o.Tracer.StartRun(ctx, &trace.RunInfo{
+		Component:     trace.ComponentScheduler,
+		Key:           run.ti.Key,
+		Gid:           int64(gid),
+		Attempt:       run.attempt,
+		Priority:      int(run.ti.GetOptions().Priority),
+		ScheduledTime: run.scheduled,
+		StartTime:     tt.clock.Now(),
+	})
+}
@@ -0,0 +1,463 @@
+package GoTask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitee.com/magicianlyx/GoTask/task"
+	"gitee.com/magicianlyx/GoTask/trace"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrWorkflowEmpty      = errors.New("workflow has no node")
+	ErrWorkflowNodeExist  = errors.New("workflow node is exist")
+	ErrWorkflowDependency = errors.New("workflow node depends on unknown node")
+	ErrWorkflowCycle      = errors.New("workflow has dependency cycle")
+)
+
+// 工作流节点状态
+type NodeStatus int
+
+const (
+	NodePending   NodeStatus = 0 // 等待上游节点结束
+	NodeRunning   NodeStatus = 1 // 已派发执行
+	NodeSucceeded NodeStatus = 2 // 执行成功
+	NodeFailed    NodeStatus = 3 // 执行失败（已用尽重试）
+	NodeSkipped   NodeStatus = 4 // 执行条件不满足或上游节点被跳过 未执行
+	NodeHalted    NodeStatus = 5 // 上游节点失败 未执行
+)
+
+func (s NodeStatus) ToString() string {
+	switch s {
+	case NodeRunning:
+		return "running"
+	case NodeSucceeded:
+		return "succeeded"
+	case NodeFailed:
+		return "failed"
+	case NodeSkipped:
+		return "skipped"
+	case NodeHalted:
+		return "halted"
+	default:
+		return "pending"
+	}
+}
+
+// 节点执行条件 results为本次工作流执行中已结束节点的执行结果
+type NodeCondition func(results map[string]*task.TaskResult) bool
+
+// 工作流节点
+type WorkflowNode struct {
+	Key       string              // 节点key 工作流内唯一
+	Task      task.ContextTaskObj // 节点任务方法
+	Deps      []string            // 上游节点key 所有上游节点执行成功后才会执行
+	Condition NodeCondition       // 执行条件 为空时总是执行
+	Options   *task.Options       // 节点任务配置
+}
+
+// 满足条件时才执行节点 返回自身
+func (n *WorkflowNode) When(cond NodeCondition) *WorkflowNode {
+	n.Condition = cond
+	return n
+}
+
+// 设置节点任务配置 返回自身
+func (n *WorkflowNode) WithOptions(options *task.Options) *WorkflowNode {
+	n.Options = options
+	return n
+}
+
+// 由节点组成的有向无环图 没有上游节点的节点在工作流被调度时执行
+// 其余节点在所有上游节点执行成功后执行 上游节点失败时其下游节点均不再执行
+type Workflow struct {
+	Name     string
+	nodes    map[string]*WorkflowNode
+	order    []string            // 节点添加顺序
+	children map[string][]string // 下游节点 校验时生成
+	err      error               // 构建过程中的错误
+}
+
+func NewWorkflow(name string) *Workflow {
+	return &Workflow{
+		Name:  name,
+		nodes: make(map[string]*WorkflowNode),
+		order: make([]string, 0),
+	}
+}
+
+// 添加节点 deps为上游节点key 返回添加的节点
+func (w *Workflow) Node(key string, obj task.ContextTaskObj, deps ...string) *WorkflowNode {
+	n := &WorkflowNode{Key: key, Task: obj, Deps: deps}
+	if _, ok := w.nodes[key]; ok {
+		if w.err == nil {
+			w.err = fmt.Errorf("%w: `%s`", ErrWorkflowNodeExist, key)
+		}
+		return n
+	}
+	w.nodes[key] = n
+	w.order = append(w.order, key)
+	return n
+}
+
+// 校验工作流 检查依赖是否存在及是否有环 并生成下游节点索引
+func (w *Workflow) Validate() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.nodes) == 0 {
+		return ErrWorkflowEmpty
+	}
+	children := make(map[string][]string)
+	waiting := make(map[string]int)
+	for _, key := range w.order {
+		for _, dep := range w.nodes[key].Deps {
+			if _, ok := w.nodes[dep]; !ok {
+				return fmt.Errorf("%w: `%s` -> `%s`", ErrWorkflowDependency, key, dep)
+			}
+			children[dep] = append(children[dep], key)
+			waiting[key]++
+		}
+	}
+
+	// 拓扑排序 无法排序的节点处于环中
+	queue := make([]string, 0)
+	for _, key := range w.order {
+		if waiting[key] == 0 {
+			queue = append(queue, key)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, c := range children[key] {
+			waiting[c]--
+			if waiting[c] == 0 {
+				queue = append(queue, c)
+			}
+		}
+	}
+	if visited != len(w.nodes) {
+		return ErrWorkflowCycle
+	}
+	w.children = children
+	return nil
+}
+
+// 节点的完整key 用于执行回调及并发执行控制
+func (w *Workflow) nodeKey(key string) string {
+	return w.Name + "/" + key
+}
+
+// 工作流节点执行状态
+type WorkflowNodeState struct {
+	Status NodeStatus
+	Result *task.TaskResult // 节点执行结果 未执行时为nil
+}
+
+type WorkflowCbArgs struct {
+	Name  string                        // 工作流名称
+	RunID string                        // 工作流执行id
+	Nodes map[string]*WorkflowNodeState // 各节点执行状态
+	Error error                         // 第一个失败节点的错误 全部成功时为nil
+}
+
+// 已添加的工作流
+type workflowEntry struct {
+	w         *Workflow
+	ctx       context.Context    // 工作流各次执行共用的context 工作流被取消或禁止时取消
+	cancel    context.CancelFunc // 取消ctx
+	l         sync.Mutex
+	runs      int  // 未结束的执行数
+	released  bool // 已释放
+	cancelled bool // 被主动取消或禁止
+}
+
+// 一次工作流执行
+type workflowRun struct {
+	id      string
+	w       *Workflow
+	e       *workflowEntry
+	ctx     context.Context // 各节点执行context的上游
+	l       sync.Mutex
+	waiting map[string]int // 未结束的上游节点数
+	nodes   map[string]*WorkflowNodeState
+	pending int        // 未结束的节点数
+	err     error      // 第一个失败节点的错误
+	span    trace.Span // 触发本次执行的跨度 作为各节点执行跨度的上游 可为nil
+}
+
+// 一次工作流节点执行
+type workflowStep struct {
+	run  *workflowRun
+	node string
+}
+
+// 节点的上游节点执行结果副本
+func (s *workflowStep) upstream() map[string]*task.TaskResult {
+	s.run.l.Lock()
+	defer s.run.l.Unlock()
+	m := make(map[string]*task.TaskResult)
+	for _, dep := range s.run.w.nodes[s.node].Deps {
+		m[dep] = s.run.nodes[dep].Result.Clone()
+	}
+	return m
+}
+
+type workflowRunKey struct{}
+
+// 获取工作流节点任务所属的工作流执行id
+func WorkflowRunID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(workflowRunKey{}).(string)
+	return id, ok
+}
+
+func (tt *TimedTask) AddWorkflowCallback(cb func(*WorkflowCbArgs)) {
+	tt.workflowCallback.Add(cb)
+}
+
+func (tt *TimedTask) DelWorkflowCallback(cb func(*WorkflowCbArgs)) {
+	tt.workflowCallback.Del(cb)
+}
+
+func (tt *TimedTask) invokeWorkflowCallback(args *WorkflowCbArgs) {
+	go func() {
+		workflowCallbacks := make([]workflowCallback, 0)
+		tt.workflowCallback.GetAll(&workflowCallbacks)
+		for _, cb := range workflowCallbacks {
+			cb(args)
+		}
+	}()
+}
+
+// 添加工作流 工作流以其名称作为key被调度 每次调度开始一次工作流执行
+// 调度任务的执行结果中runId为本次工作流执行id 添加后不应再修改工作流
+// 取消或禁止工作流时 执行中的节点收到取消信号 其余节点不再执行 且不触发工作流回调
+func (tt *TimedTask) AddWorkflow(w *Workflow, sche task.ISchedule, options ...*task.Options) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	e := &workflowEntry{w: w}
+	e.ctx, e.cancel = context.WithCancel(tt.ctx)
+	trigger := func(ctx context.Context) (map[string]interface{}, error) {
+		id := tt.startWorkflow(ctx, e)
+		return map[string]interface{}{"runId": id}, nil
+	}
+	_, err := tt.addWithCb(task.NewContextTaskInfo(w.Name, trigger, sche).WithClock(tt.clock).WithOptions(options...), true)
+	if err != nil {
+		e.cancel()
+		return err
+	}
+	tt.workflows.Store(w.Name, e)
+	// 调度任务可能在保存前已执行完毕并被清除
+	tt.releaseWorkflow(e)
+	return nil
+}
+
+// 取消工作流 执行中的节点收到取消信号 等待中的节点不再执行 并删除节点的执行记录及执行统计
+func (tt *TimedTask) cancelWorkflow(name string) {
+	v, ok := tt.workflows.Load(name)
+	if !ok {
+		return
+	}
+	e := v.(*workflowEntry)
+	e.l.Lock()
+	e.cancelled = true
+	e.l.Unlock()
+	e.cancel()
+	tt.workflows.Delete(name)
+	tt.forgetWorkflow(e.w)
+}
+
+// 删除工作流所有节点的执行记录及执行统计
+func (tt *TimedTask) forgetWorkflow(w *Workflow) {
+	for key := range w.nodes {
+		tt.forget(w.nodeKey(key))
+	}
+}
+
+// 调度任务已被清除且没有未结束的执行时释放工作流 节点的执行记录及执行统计仍保留
+func (tt *TimedTask) releaseWorkflow(e *workflowEntry) {
+	v, _ := tt.workflows.Load(e.w.Name)
+	current := v == e
+	e.l.Lock()
+	if e.released || e.runs > 0 || (current && tt.tMap.IsExist(e.w.Name)) {
+		e.l.Unlock()
+		return
+	}
+	e.released = true
+	cancelled := e.cancelled
+	e.l.Unlock()
+	e.cancel()
+	if _, ok := tt.workflows.Load(e.w.Name); cancelled && !ok {
+		// 取消后才结束的节点执行记录 同名工作流被重新添加时保留其记录
+		tt.forgetWorkflow(e.w)
+	}
+}
+
+// 开始一次工作流执行 派发所有没有上游节点的节点 返回执行id
+func (tt *TimedTask) startWorkflow(ctx context.Context, e *workflowEntry) string {
+	w := e.w
+	e.l.Lock()
+	e.runs++
+	e.l.Unlock()
+	run := &workflowRun{
+		id:      fmt.Sprintf("%s-%d", w.Name, atomic.AddInt64(&tt.workflowSeq, 1)),
+		w:       w,
+		e:       e,
+		ctx:     e.ctx,
+		waiting: make(map[string]int),
+		nodes:   make(map[string]*WorkflowNodeState),
+		pending: len(w.nodes),
+	}
+	run.span, _ = trace.SpanFromContext(ctx)
+	ready := make([]string, 0)
+	run.l.Lock()
+	for _, key := range w.order {
+		run.nodes[key] = &WorkflowNodeState{Status: NodePending}
+		run.waiting[key] = len(w.nodes[key].Deps)
+	}
+	for _, key := range w.order {
+		if run.waiting[key] == 0 {
+			ready = run.decide(key, ready)
+		}
+	}
+	run.l.Unlock()
+	tt.dispatchSteps(run, ready)
+	return run.id
+}
+
+// 节点执行结束 派发满足条件的下游节点
+func (tt *TimedTask) stepDone(step *workflowStep, tr *task.TaskResult) {
+	run := step.run
+	status := NodeSucceeded
+	if tr.Err != nil {
+		status = NodeFailed
+	}
+	run.l.Lock()
+	run.nodes[step.node].Result = tr
+	if tr.Err != nil && run.err == nil {
+		run.err = tr.Err
+	}
+	ready := run.resolve(step.node, status, make([]string, 0))
+	run.l.Unlock()
+	tt.dispatchSteps(run, ready)
+}
+
+// 派发节点执行 所有节点结束时触发工作流回调
+// 在锁外判断节点的执行条件 不满足时跳过节点 工作流被取消时不再派发节点也不触发回调
+func (tt *TimedTask) dispatchSteps(run *workflowRun, ready []string) {
+	for len(ready) > 0 {
+		key := ready[0]
+		ready = ready[1:]
+		node := run.w.nodes[key]
+		status := NodeRunning
+		if run.ctx.Err() != nil {
+			status = NodeHalted
+		} else if node.Condition != nil && !node.Condition(run.results()) {
+			status = NodeSkipped
+		}
+		if status != NodeRunning {
+			run.l.Lock()
+			ready = run.resolve(key, status, ready)
+			run.l.Unlock()
+			continue
+		}
+		ti := &task.TaskInfo{
+			Key:     run.w.nodeKey(key),
+			CtxTask: node.Task,
+			AddTime: tt.clock.Now(),
+		}
+		ti.SetClock(tt.clock)
+		ti.WithOptions(node.Options)
+		r := newTaskRun(ti)
+		r.step = &workflowStep{run: run, node: key}
+		// 可能在执行线程中调用 异步派发防止阻塞执行线程
+		go tt.dispatch(r)
+	}
+	if args := run.finished(); args != nil {
+		cancelled := run.ctx.Err() != nil
+		e := run.e
+		e.l.Lock()
+		e.runs--
+		e.l.Unlock()
+		tt.releaseWorkflow(e)
+		if !cancelled {
+			tt.invokeWorkflowCallback(args)
+		}
+	}
+}
+
+// 工作流被取消后放弃节点的一次执行
+func (tt *TimedTask) dropStep(run *taskRun) {
+	tt.finish(run.ti.Key)
+	tt.stepDone(run.step, &task.TaskResult{Err: run.step.run.ctx.Err()})
+}
+
+// 所有上游节点结束后决定节点是否执行 上游节点均成功时加入ready 调用方需持有锁
+// 执行条件由dispatchSteps在锁外判断
+func (r *workflowRun) decide(key string, ready []string) []string {
+	node := r.w.nodes[key]
+	status := NodeRunning
+	for _, dep := range node.Deps {
+		switch r.nodes[dep].Status {
+		case NodeSucceeded:
+		case NodeFailed, NodeHalted:
+			status = NodeHalted
+		default:
+			if status == NodeRunning {
+				status = NodeSkipped
+			}
+		}
+	}
+	if status == NodeRunning {
+		r.nodes[key].Status = NodeRunning
+		return append(ready, key)
+	}
+	return r.resolve(key, status, ready)
+}
+
+// 节点结束 通知其下游节点 调用方需持有锁
+func (r *workflowRun) resolve(key string, status NodeStatus, ready []string) []string {
+	r.nodes[key].Status = status
+	r.pending--
+	for _, c := range r.w.children[key] {
+		r.waiting[c]--
+		if r.waiting[c] == 0 {
+			ready = r.decide(c, ready)
+		}
+	}
+	return ready
+}
+
+// 已结束节点的执行结果副本
+func (r *workflowRun) results() map[string]*task.TaskResult {
+	r.l.Lock()
+	defer r.l.Unlock()
+	m := make(map[string]*task.TaskResult)
+	for key, s := range r.nodes {
+		if s.Result != nil {
+			m[key] = s.Result.Clone()
+		}
+	}
+	return m
+}
+
+// 所有节点结束时返回工作流回调参数 否则返回nil 只会返回一次
+func (r *workflowRun) finished() *WorkflowCbArgs {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if r.pending != 0 {
+		return nil
+	}
+	r.pending = -1
+	nodes := make(map[string]*WorkflowNodeState)
+	for key, s := range r.nodes {
+		nodes[key] = &WorkflowNodeState{Status: s.Status, Result: s.Result.Clone()}
+	}
+	return &WorkflowCbArgs{Name: r.w.Name, RunID: r.id, Nodes: nodes, Error: r.err}
+}
@@ -0,0 +1,221 @@
+package GoTask
+
+import (
+	"context"
+	"errors"
+	"gitee.com/magicianlyx/GoTask/task"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkflow_Validate(t *testing.T) {
+	nop := func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, nil
+	}
+	if err := NewWorkflow("empty").Validate(); err != ErrWorkflowEmpty {
+		t.Errorf("empty: %v", err)
+	}
+
+	w := NewWorkflow("dup")
+	w.Node("a", nop)
+	w.Node("a", nop)
+	if err := w.Validate(); !errors.Is(err, ErrWorkflowNodeExist) {
+		t.Errorf("duplicate: %v", err)
+	}
+
+	w = NewWorkflow("unknown")
+	w.Node("a", nop, "missing")
+	if err := w.Validate(); !errors.Is(err, ErrWorkflowDependency) {
+		t.Errorf("unknown dependency: %v", err)
+	}
+
+	w = NewWorkflow("cycle")
+	w.Node("root", nop)
+	w.Node("a", nop, "root", "b")
+	w.Node("b", nop, "a")
+	if err := w.Validate(); err != ErrWorkflowCycle {
+		t.Errorf("cycle: %v", err)
+	}
+}
+
+func TestTimedTask_AddWorkflow(t *testing.T) {
+	tt := NewTimedTask(2)
+	defer tt.Stop()
+	done := make(chan *WorkflowCbArgs, 1)
+	tt.AddWorkflowCallback(func(args *WorkflowCbArgs) {
+		done <- args
+	})
+
+	w := NewWorkflow("etl")
+	var l sync.Mutex
+	runIDs := make(map[string]string)
+	node := func(key string, err error) task.ContextTaskObj {
+		return func(ctx context.Context) (map[string]interface{}, error) {
+			id, _ := WorkflowRunID(ctx)
+			l.Lock()
+			runIDs[key] = id
+			l.Unlock()
+			if ec, ok := task.FromContext(ctx); !ok || len(ec.Upstream) != len(w.nodes[key].Deps) {
+				t.Errorf("%s: unexpected upstream results %+v", key, ec)
+			} else if key == "transform" && ec.Upstream["extract"].Result["rows"] != 3 {
+				t.Errorf("transform should see extract result, got %+v", ec.Upstream)
+			}
+			return map[string]interface{}{"rows": 3}, err
+		}
+	}
+	fail := errors.New("load failed")
+	w.Node("extract", node("extract", nil))
+	w.Node("transform", node("transform", nil), "extract")
+	w.Node("audit", node("audit", nil), "extract").When(func(results map[string]*task.TaskResult) bool {
+		return results["extract"].Result["rows"].(int) > 10
+	})
+	w.Node("load", node("load", fail), "transform")
+	w.Node("index", node("index", nil), "load")
+	w.Node("report", node("report", nil), "transform", "audit")
+	if err := tt.AddWorkflow(w, task.NewSpecTimeSchedule(10*time.Millisecond, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	var args *WorkflowCbArgs
+	select {
+	case args = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("workflow not finished")
+	}
+	want := map[string]NodeStatus{
+		"extract":   NodeSucceeded,
+		"transform": NodeSucceeded,
+		"audit":     NodeSkipped,
+		"load":      NodeFailed,
+		"index":     NodeHalted,
+		"report":    NodeSkipped,
+	}
+	for key, status := range want {
+		if got := args.Nodes[key].Status; got != status {
+			t.Errorf("%s: got %s, want %s", key, got.ToString(), status.ToString())
+		}
+	}
+	if args.Error != fail || args.RunID == "" {
+		t.Errorf("unexpected run result: %+v", args)
+	}
+	l.Lock()
+	defer l.Unlock()
+	if len(runIDs) != 3 {
+		t.Errorf("unexpected executed nodes: %v", runIDs)
+	}
+	for key, id := range runIDs {
+		if id != args.RunID {
+			t.Errorf("%s: run id %s, want %s", key, id, args.RunID)
+		}
+	}
+
+	// 工作流结束后保留节点的执行记录及统计 取消工作流时一并删除
+	if len(tt.GetHistory("etl/extract", 0)) != 1 {
+		t.Errorf("node history should be kept after the workflow finished")
+	}
+	if _, ok := tt.GetRunStats("etl/extract"); !ok {
+		t.Errorf("node stats should be kept after the workflow finished")
+	}
+	tt.Cancel("etl")
+	if _, ok := tt.GetRunStats("etl/extract"); ok || len(tt.GetHistory("etl/extract", 0)) != 0 {
+		t.Errorf("node history and stats should be removed with the workflow")
+	}
+}
+
+func TestTimedTask_CancelWorkflow(t *testing.T) {
+	tt := NewTimedTask(2)
+	defer tt.Stop()
+	finished := make(chan *WorkflowCbArgs, 1)
+	tt.AddWorkflowCallback(func(args *WorkflowCbArgs) {
+		finished <- args
+	})
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	var downstream int32
+	w := NewWorkflow("wf")
+	w.Node("wait", func(ctx context.Context) (map[string]interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	w.Node("next", func(ctx context.Context) (map[string]interface{}, error) {
+		atomic.AddInt32(&downstream, 1)
+		return nil, nil
+	}, "wait")
+	if err := tt.AddWorkflow(w, task.NewSpecSchedule(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	tt.Execute("wf")
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("node not started")
+	}
+
+	tt.Cancel("wf")
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("unexpected node context error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("running node not cancelled")
+	}
+	select {
+	case args := <-finished:
+		t.Errorf("callback fired for cancelled workflow: %+v", args)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if atomic.LoadInt32(&downstream) != 0 {
+		t.Errorf("downstream node executed after cancel")
+	}
+}
+
+func TestTimedTask_WorkflowConditionOutsideLock(t *testing.T) {
+	tt := NewTimedTask(2)
+	defer tt.Stop()
+	bDone := make(chan struct{})
+	tt.AddExecuteCallback(func(a *task.ExecuteCbArgs) {
+		if a.Key == "cond/b" {
+			close(bDone)
+		}
+	})
+	done := make(chan *WorkflowCbArgs, 1)
+	tt.AddWorkflowCallback(func(args *WorkflowCbArgs) {
+		done <- args
+	})
+
+	noop := func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, nil
+	}
+	w := NewWorkflow("cond")
+	w.Node("a", noop)
+	w.Node("b", func(ctx context.Context) (map[string]interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, nil
+	})
+	// 等待兄弟节点结束的执行条件不应阻塞其结束
+	w.Node("c", noop, "a").When(func(results map[string]*task.TaskResult) bool {
+		select {
+		case <-bDone:
+			return true
+		case <-time.After(time.Second):
+			return false
+		}
+	})
+	if err := tt.AddWorkflow(w, task.NewSpecTimeSchedule(10*time.Millisecond, 1)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case args := <-done:
+		if args.Nodes["c"].Status != NodeSucceeded {
+			t.Errorf("condition blocked sibling node: %s", args.Nodes["c"].Status.ToString())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("workflow not finished")
+	}
+}